@@ -0,0 +1,15 @@
+module github.com/mitranim/try/cmd
+
+go 1.25.0
+
+require (
+	github.com/mitranim/try v0.2.0
+	github.com/mitranim/try/tryvet v0.2.0
+	golang.org/x/tools v0.47.0
+)
+
+require (
+	github.com/pkg/errors v0.9.1 // indirect
+	golang.org/x/mod v0.37.0 // indirect
+	golang.org/x/sync v0.21.0 // indirect
+)
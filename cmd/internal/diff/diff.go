@@ -0,0 +1,189 @@
+/*
+Minimal line-based unified diff, used by the refactoring commands for their
+dry-run modes.
+*/
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Amount of unchanged lines printed around each change.
+const context = 3
+
+/*
+Returns a unified diff between two texts, in the format of `diff -u`. Returns
+nil if the texts are identical.
+*/
+func Unified(oldName, newName string, oldSrc, newSrc []byte) []byte {
+	if bytes.Equal(oldSrc, newSrc) {
+		return nil
+	}
+
+	prev := splitLines(string(oldSrc))
+	next := splitLines(string(newSrc))
+	ops := edits(prev, next)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %v\n+++ %v\n", oldName, newName)
+
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		// Extend the hunk while changes are separated by at most twice the
+		// context, so that adjacent hunks merge.
+		end := start
+		for ind := start; ind < len(ops); ind++ {
+			if ops[ind].kind != ' ' {
+				end = ind + 1
+			} else if ind-end >= 2*context {
+				break
+			}
+		}
+
+		from := start - context
+		if from < 0 {
+			from = 0
+		}
+		to := end + context
+		if to > len(ops) {
+			to = len(ops)
+		}
+		writeHunk(&buf, ops[from:to])
+		start = to
+	}
+	return buf.Bytes()
+}
+
+type op struct {
+	kind rune
+	line string
+	prev int
+	next int
+}
+
+func writeHunk(buf *bytes.Buffer, ops []op) {
+	var prevLen, nextLen int
+	for _, val := range ops {
+		if val.kind != '+' {
+			prevLen++
+		}
+		if val.kind != '-' {
+			nextLen++
+		}
+	}
+
+	fmt.Fprintf(buf, "@@ -%v +%v @@\n", span(ops[0].prev, prevLen), span(ops[0].next, nextLen))
+	for _, val := range ops {
+		buf.WriteRune(val.kind)
+		buf.WriteString(val.line)
+		if !strings.HasSuffix(val.line, "\n") {
+			buf.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func span(start, size int) string {
+	if size == 0 {
+		return fmt.Sprintf(`%v,0`, start)
+	}
+	if size == 1 {
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf(`%v,%v`, start+1, size)
+}
+
+func splitLines(src string) []string {
+	var out []string
+	for len(src) > 0 {
+		ind := strings.IndexByte(src, '\n')
+		if ind < 0 {
+			out = append(out, src)
+			break
+		}
+		out = append(out, src[:ind+1])
+		src = src[ind+1:]
+	}
+	return out
+}
+
+/*
+Myers' O(ND) algorithm. Returns the full edit script, including unchanged
+lines, each annotated with its 0-based position in both inputs.
+*/
+func edits(prev, next []string) []op {
+	size := len(prev) + len(next)
+	offset := size + 1
+	front := make([]int, 2*size+2)
+	var trace [][]int
+
+	for dist := 0; dist <= size; dist++ {
+		trace = append(trace, append([]int(nil), front...))
+		for diag := -dist; diag <= dist; diag += 2 {
+			var x int
+			if diag == -dist || (diag != dist && front[offset+diag-1] < front[offset+diag+1]) {
+				x = front[offset+diag+1]
+			} else {
+				x = front[offset+diag-1] + 1
+			}
+			y := x - diag
+			for x < len(prev) && y < len(next) && prev[x] == next[y] {
+				x++
+				y++
+			}
+			front[offset+diag] = x
+			if x >= len(prev) && y >= len(next) {
+				return backtrack(prev, next, trace, offset, dist)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(prev, next []string, trace [][]int, offset, dist int) []op {
+	var out []op
+	x, y := len(prev), len(next)
+
+	for ; dist >= 0; dist-- {
+		front := trace[dist]
+		diag := x - y
+
+		var prevDiag int
+		if diag == -dist || (diag != dist && front[offset+diag-1] < front[offset+diag+1]) {
+			prevDiag = diag + 1
+		} else {
+			prevDiag = diag - 1
+		}
+
+		prevX := front[offset+prevDiag]
+		prevY := prevX - prevDiag
+		if dist == 0 {
+			prevX, prevY = 0, 0
+		}
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			out = append(out, op{' ', prev[x], x, y})
+		}
+		if dist > 0 {
+			if x == prevX {
+				y--
+				out = append(out, op{'+', next[y], x, y})
+			} else {
+				x--
+				out = append(out, op{'-', prev[x], x, y})
+			}
+		}
+	}
+
+	for ind, end := 0, len(out)-1; ind < end; ind, end = ind+1, end-1 {
+		out[ind], out[end] = out[end], out[ind]
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+/*
+Text-based source rewriting, shared by the refactoring commands. Edits are
+applied to the original bytes rather than to the AST, which preserves comments
+and layout outside the edited ranges.
+*/
+package srcedit
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+
+	"github.com/mitranim/try"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/imports"
+)
+
+// Replaces the bytes between `Start` and `End` with `Text`.
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+/*
+Applies non-overlapping edits, adds the given imports, and formats the result.
+Imports are grouped and pruned like "goimports" would, since edits may leave
+some of them unused. Panics if the result doesn't parse.
+*/
+func Apply(src []byte, edits []Edit, paths ...string) []byte {
+	edits = append([]Edit(nil), edits...)
+	sort.SliceStable(edits, func(one, two int) bool {
+		return edits[one].Start > edits[two].Start
+	})
+
+	out := append([]byte(nil), src...)
+	for _, val := range edits {
+		out = append(out[:val.Start], append([]byte(val.Text), out[val.End:]...)...)
+	}
+
+	fset := token.NewFileSet()
+	file := try.Interface(parser.ParseFile(fset, ``, out, parser.ParseComments)).(*ast.File)
+	for _, path := range paths {
+		astutil.AddImport(fset, file, path)
+	}
+
+	var buf bytes.Buffer
+	try.To(format.Node(&buf, fset, file))
+	return try.ByteSlice(imports.Process(``, buf.Bytes(), &imports.Options{
+		Comments:  true,
+		TabIndent: true,
+		TabWidth:  8,
+	}))
+}
+
+// Extends the range of a statement to whole lines, so that deleting it doesn't
+// leave a blank line behind.
+func Lines(src []byte, start, end int) (int, int) {
+	for start > 0 && (src[start-1] == ' ' || src[start-1] == '\t') {
+		start--
+	}
+	for end < len(src) && (src[end] == ' ' || src[end] == '\t' || src[end] == '\r') {
+		end++
+	}
+	if end < len(src) && src[end] == '\n' {
+		end++
+	}
+	return start, end
+}
+
+/*
+Name under which the file imports the given package, or the provided default if
+it's imported without a name or not at all.
+*/
+func ImportName(file *ast.File, path, def string) string {
+	for _, spec := range file.Imports {
+		val, _ := strconv.Unquote(spec.Path.Value)
+		if val == path && spec.Name != nil {
+			return spec.Name.Name
+		}
+	}
+	return def
+}
@@ -0,0 +1,157 @@
+/*
+Type-level knowledge of package "try", shared by the refactoring commands.
+*/
+package trytypes
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+)
+
+// True if the type is the built-in `error`.
+func IsError(typ types.Type) bool {
+	return typ != nil && types.Identical(typ, types.Universe.Lookup(`error`).Type())
+}
+
+/*
+Returns the name of the typed "try" function for values of the given type, such
+as "String" for `string`, or "" if there's none.
+*/
+func TryFunc(typ types.Type) string {
+	if typ == nil {
+		return ``
+	}
+	if slice, ok := typ.Underlying().(*types.Slice); ok && types.Identical(typ, slice) {
+		name := tryBasic(slice.Elem())
+		if name == `` {
+			return ``
+		}
+		return name + `Slice`
+	}
+	return tryBasic(typ)
+}
+
+func tryBasic(typ types.Type) string {
+	if iface, ok := typ.(*types.Interface); ok && iface.Empty() {
+		return `Interface`
+	}
+	basic, ok := typ.(*types.Basic)
+	if !ok {
+		return ``
+	}
+	switch basic.Kind() {
+	case types.Bool:
+		return `Bool`
+	case types.Uint8:
+		return `Byte`
+	case types.Uint16:
+		return `Uint16`
+	case types.Uint32:
+		return `Uint32`
+	case types.Uint64:
+		return `Uint64`
+	case types.Int8:
+		return `Int8`
+	case types.Int16:
+		return `Int16`
+	case types.Int32:
+		return `Int32`
+	case types.Int64:
+		return `Int64`
+	case types.Float32:
+		return `Float32`
+	case types.Float64:
+		return `Float64`
+	case types.Complex64:
+		return `Complex64`
+	case types.Complex128:
+		return `Complex128`
+	case types.String:
+		return `String`
+	case types.Int:
+		return `Int`
+	case types.Uint:
+		return `Uint`
+	case types.Uintptr:
+		return `Uintptr`
+	default:
+		return ``
+	}
+}
+
+// Import path of package "try".
+const Path = `github.com/mitranim/try`
+
+/*
+True for `try.To` and the typed "try" functions such as `try.String`, which
+panic on a non-nil error.
+*/
+func IsPanicking(fun *types.Func) bool {
+	if fun == nil || fun.Pkg() == nil || fun.Pkg().Path() != Path {
+		return false
+	}
+	if fun.Name() == `To` {
+		return true
+	}
+	sig := fun.Type().(*types.Signature)
+	params := sig.Params()
+	return sig.Recv() == nil &&
+		params.Len() == 2 &&
+		sig.Results().Len() == 1 &&
+		IsError(params.At(1).Type()) &&
+		types.Identical(params.At(0).Type(), sig.Results().At(0).Type())
+}
+
+// True if the object is the function of package "try" with this name.
+func IsFunc(obj types.Object, name string) bool {
+	fun, _ := obj.(*types.Func)
+	return fun != nil && fun.Pkg() != nil && fun.Pkg().Path() == Path && fun.Name() == name
+}
+
+/*
+True if the package still type-checks after replacing the source of one of its
+files. Used to refuse rewrites that would break compilation. The file must
+belong to the file set.
+*/
+func Compiles(fset *token.FileSet, path string, files []*ast.File, file *ast.File, src []byte, imp types.Importer) bool {
+	out, err := parser.ParseFile(fset, fset.File(file.Pos()).Name(), src, parser.SkipObjectResolution)
+	if err != nil {
+		return false
+	}
+
+	list := make([]*ast.File, 0, len(files))
+	for _, val := range files {
+		if val == file {
+			val = out
+		}
+		list = append(list, val)
+	}
+
+	conf := types.Config{Importer: imp}
+	_, err = conf.Check(path, fset, list, nil)
+	return err == nil
+}
+
+/*
+Importer which resolves the imports of an already type-checked package, and
+falls back on another importer for anything else, such as package "try" when
+it's not imported yet.
+*/
+type Importer struct {
+	Pkg      *types.Package
+	Fallback types.Importer
+}
+
+// Implement `types.Importer`.
+func (self Importer) Import(path string) (*types.Package, error) {
+	if self.Pkg != nil {
+		for _, val := range self.Pkg.Imports() {
+			if val.Path() == path {
+				return val, nil
+			}
+		}
+	}
+	return self.Fallback.Import(path)
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/mitranim/try"
+	"github.com/mitranim/try/cmd/internal/srcedit"
+	"github.com/mitranim/try/cmd/internal/trytypes"
+)
+
+const errorsPath = `github.com/pkg/errors`
+
+/*
+Rewrites a type-checked file into the "try" style. Returns the new source, or
+nil if nothing was changed. Each function returning an `error` as its last
+result is rewritten independently; functions where the rewrite isn't provably
+safe are left as-is, and so are functions whose rewrite fails `verify`, which
+should report whether the new source of the file type-checks.
+*/
+func fixFile(fset *token.FileSet, file *ast.File, info *types.Info, src []byte, verify func([]byte) bool) []byte {
+	fixer := fixer{fset: fset, info: info, src: src, pkg: srcedit.ImportName(file, trytypes.Path, `try`)}
+
+	var funcs [][]srcedit.Edit
+	for _, decl := range file.Decls {
+		fun, ok := decl.(*ast.FuncDecl)
+		if !ok || fun.Body == nil {
+			continue
+		}
+		edits := fixer.fixFunc(fun)
+		if len(edits) > 0 {
+			funcs = append(funcs, edits)
+		}
+	}
+	if len(funcs) == 0 {
+		return nil
+	}
+
+	// Usually, all rewrites are fine together. Otherwise, they're verified one
+	// by one, keeping only those that compile.
+	var all []srcedit.Edit
+	for _, edits := range funcs {
+		all = append(all, edits...)
+	}
+	out := fixer.apply(all, verify)
+	if out != nil {
+		return out
+	}
+
+	var kept []srcedit.Edit
+	for _, edits := range funcs {
+		next := append(kept[:len(kept):len(kept)], edits...)
+		val := fixer.apply(next, verify)
+		if val != nil {
+			kept, out = next, val
+		}
+	}
+	return out
+}
+
+type fixer struct {
+	fset *token.FileSet
+	info *types.Info
+	src  []byte
+	pkg  string
+}
+
+// Single "if err != nil { return ... }" check that can be replaced.
+type check struct {
+	start ast.Node
+	end   ast.Node
+	errs  *types.Var
+	stmt  string
+	msg   string
+}
+
+func (self *fixer) fixFunc(decl *ast.FuncDecl) []srcedit.Edit {
+	sig, _ := self.info.Defs[decl.Name].Type().(*types.Signature)
+	if sig == nil || sig.Results().Len() == 0 {
+		return nil
+	}
+	res := sig.Results().At(sig.Results().Len() - 1)
+	if !trytypes.IsError(res.Type()) {
+		return nil
+	}
+
+	name := res.Name()
+	if name == `_` {
+		return nil
+	}
+	named := name != ``
+	if !named {
+		name = `err`
+		if sig.Params().Len() > 0 && paramNamed(sig, name) {
+			return nil
+		}
+	}
+
+	// A panic skips the explicit zero values of returns, so results assigned
+	// earlier would be returned as-is.
+	if named && self.assignsResults(decl.Body, sig) {
+		return nil
+	}
+
+	// Checks left as-is keep using their variables, so grouping must come
+	// before deciding which variables leak.
+	checks, msg := groupMessages(self.findChecks(decl.Body, sig))
+	checks = self.dropLeaky(decl.Body, sig, checks)
+	if len(checks) == 0 {
+		return nil
+	}
+
+	// Naming the results must not conflict with remaining local variables.
+	if !named && self.declaresRemaining(decl, name, checks) {
+		return nil
+	}
+
+	var edits []srcedit.Edit
+	if !named {
+		edits = append(edits, self.nameResults(decl.Type, name))
+	}
+
+	rec := self.pkg + `.Rec(&` + name + `)`
+	if msg != `` {
+		rec = self.pkg + `.RecWithMessage(&` + name + `, ` + msg + `)`
+	}
+	edits = append(edits, srcedit.Edit{
+		Start: self.offset(decl.Body.Lbrace) + 1,
+		End:   self.offset(decl.Body.Lbrace) + 1,
+		Text:  "\ndefer " + rec,
+	})
+
+	for _, val := range checks {
+		edits = append(edits, srcedit.Edit{
+			Start: self.offset(val.start.Pos()),
+			End:   self.offset(val.end.End()),
+			Text:  val.stmt,
+		})
+	}
+	return edits
+}
+
+// Finds replaceable checks in all blocks of the body, excluding closures.
+func (self *fixer) findChecks(body *ast.BlockStmt, sig *types.Signature) []check {
+	var out []check
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.BlockStmt:
+			out = append(out, self.blockChecks(node.List, sig)...)
+		case *ast.CaseClause:
+			out = append(out, self.blockChecks(node.Body, sig)...)
+		case *ast.CommClause:
+			out = append(out, self.blockChecks(node.Body, sig)...)
+		}
+		return true
+	})
+	return out
+}
+
+func (self *fixer) blockChecks(list []ast.Stmt, sig *types.Signature) []check {
+	var out []check
+	for ind := 0; ind < len(list); ind++ {
+		stmt, ok := list[ind].(*ast.IfStmt)
+		if !ok {
+			continue
+		}
+
+		// if err := someFunc(); err != nil { return err }
+		if stmt.Init != nil {
+			val, ok := self.check(stmt.Init, stmt, sig)
+			if ok {
+				out = append(out, val)
+			}
+			continue
+		}
+
+		// err := someFunc()
+		// if err != nil { return err }
+		if ind > 0 {
+			val, ok := self.check(list[ind-1], stmt, sig)
+			if ok {
+				out = append(out, val)
+			}
+		}
+	}
+	return out
+}
+
+func (self *fixer) check(prev ast.Stmt, stmt *ast.IfStmt, sig *types.Signature) (check, bool) {
+	assign, ok := prev.(*ast.AssignStmt)
+	if !ok || len(assign.Rhs) != 1 || stmt.Else != nil {
+		return check{}, false
+	}
+	if assign.Tok != token.DEFINE && assign.Tok != token.ASSIGN {
+		return check{}, false
+	}
+	call, ok := assign.Rhs[0].(*ast.CallExpr)
+	if !ok {
+		return check{}, false
+	}
+
+	errIdent, ok := assign.Lhs[len(assign.Lhs)-1].(*ast.Ident)
+	if !ok {
+		return check{}, false
+	}
+	errVar, _ := self.object(errIdent).(*types.Var)
+	if errVar == nil || !trytypes.IsError(errVar.Type()) || !self.isNilCheck(stmt.Cond, errVar) {
+		return check{}, false
+	}
+
+	msg, ok := self.returnsErr(stmt.Body, errVar, sig)
+	if !ok {
+		return check{}, false
+	}
+
+	var text string
+	switch len(assign.Lhs) {
+	case 1:
+		text = self.pkg + `.To(` + self.text(call) + `)`
+
+	case 2:
+		if stmt.Init != nil {
+			return check{}, false
+		}
+		tuple, _ := self.info.TypeOf(call).(*types.Tuple)
+		if tuple == nil || tuple.Len() != 2 {
+			return check{}, false
+		}
+		fun := trytypes.TryFunc(tuple.At(0).Type())
+		if fun == `` {
+			return check{}, false
+		}
+		tok := ` = `
+		if ident, ok := assign.Lhs[0].(*ast.Ident); ok && self.info.Defs[ident] != nil {
+			tok = ` := `
+		}
+		text = self.text(assign.Lhs[0]) + tok + self.pkg + `.` + fun + `(` + self.text(call) + `)`
+
+	default:
+		return check{}, false
+	}
+
+	start := ast.Node(prev)
+	if stmt.Init != nil {
+		start = stmt
+	}
+	return check{start: start, end: stmt, errs: errVar, stmt: text, msg: msg}, true
+}
+
+func (self *fixer) isNilCheck(cond ast.Expr, errVar *types.Var) bool {
+	bin, ok := cond.(*ast.BinaryExpr)
+	if !ok || bin.Op != token.NEQ {
+		return false
+	}
+	ident, ok := bin.X.(*ast.Ident)
+	if !ok || self.object(ident) != errVar {
+		return false
+	}
+	nilIdent, ok := bin.Y.(*ast.Ident)
+	return ok && nilIdent.Name == `nil`
+}
+
+/*
+Matches a body consisting of a single return statement that returns zero values
+followed by the error, optionally wrapped via `errors.WithMessage` with a
+constant message. Returns the message source text, if any.
+*/
+func (self *fixer) returnsErr(body *ast.BlockStmt, errVar *types.Var, sig *types.Signature) (string, bool) {
+	if len(body.List) != 1 {
+		return ``, false
+	}
+	ret, ok := body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != sig.Results().Len() {
+		return ``, false
+	}
+
+	for _, val := range ret.Results[:len(ret.Results)-1] {
+		if !isZero(val) {
+			return ``, false
+		}
+	}
+
+	last := ret.Results[len(ret.Results)-1]
+	if ident, ok := last.(*ast.Ident); ok && self.object(ident) == errVar {
+		return ``, true
+	}
+
+	call, ok := last.(*ast.CallExpr)
+	if !ok || len(call.Args) != 2 || !self.isWithMessage(call.Fun) {
+		return ``, false
+	}
+	ident, ok := call.Args[0].(*ast.Ident)
+	if !ok || self.object(ident) != errVar {
+		return ``, false
+	}
+	lit, ok := call.Args[1].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ``, false
+	}
+	return lit.Value, true
+}
+
+func (self *fixer) isWithMessage(expr ast.Expr) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != `WithMessage` {
+		return false
+	}
+	fun, _ := self.info.Uses[sel.Sel].(*types.Func)
+	return fun != nil && fun.Pkg() != nil && fun.Pkg().Path() == errorsPath
+}
+
+/*
+Drops checks of local error variables that are also used outside the checks,
+since removing their assignments would change behavior.
+*/
+func (self *fixer) dropLeaky(body *ast.BlockStmt, sig *types.Signature, checks []check) []check {
+	inside := func(node ast.Node, owner *types.Var) bool {
+		for _, val := range checks {
+			if val.errs == owner && node.Pos() >= val.start.Pos() && node.End() <= val.end.End() {
+				return true
+			}
+		}
+		return false
+	}
+
+	leaky := map[*types.Var]bool{}
+	ast.Inspect(body, func(node ast.Node) bool {
+		ident, ok := node.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		owner, _ := self.object(ident).(*types.Var)
+		if owner == nil || !trytypes.IsError(owner.Type()) || isResult(sig, owner) {
+			return true
+		}
+		if !inside(ident, owner) {
+			leaky[owner] = true
+		}
+		return true
+	})
+
+	var out []check
+	for _, val := range checks {
+		if !leaky[val.errs] {
+			out = append(out, val)
+		}
+	}
+	return out
+}
+
+/*
+Checks that all use the same message are collapsed into one deferral. When the
+messages differ, only the checks without a message are rewritten.
+*/
+func groupMessages(checks []check) ([]check, string) {
+	msgs := map[string]bool{}
+	for _, val := range checks {
+		msgs[val.msg] = true
+	}
+	if len(msgs) <= 1 {
+		if len(checks) > 0 {
+			return checks, checks[0].msg
+		}
+		return nil, ``
+	}
+
+	var out []check
+	for _, val := range checks {
+		if val.msg == `` {
+			out = append(out, val)
+		}
+	}
+	return out, ``
+}
+
+// True if the function body declares a variable with this name at the top
+// level, which would conflict with a result of the same name.
+func (self *fixer) declaresRemaining(decl *ast.FuncDecl, name string, checks []check) bool {
+	scope := self.info.Scopes[decl.Type]
+	if scope == nil {
+		return false
+	}
+	obj := scope.Lookup(name)
+	if obj == nil {
+		return false
+	}
+	for _, val := range checks {
+		if val.errs == obj {
+			return false
+		}
+	}
+	return true
+}
+
+func (self *fixer) nameResults(typ *ast.FuncType, name string) srcedit.Edit {
+	list := typ.Results.List
+	var buf bytes.Buffer
+	buf.WriteString(`(`)
+	for ind, field := range list {
+		if ind > 0 {
+			buf.WriteString(`, `)
+		}
+		if ind == len(list)-1 {
+			buf.WriteString(name + ` `)
+		} else {
+			buf.WriteString(`_ `)
+		}
+		buf.WriteString(self.text(field.Type))
+	}
+	buf.WriteString(`)`)
+
+	return srcedit.Edit{
+		Start: self.offset(typ.Results.Pos()),
+		End:   self.offset(typ.Results.End()),
+		Text:  buf.String(),
+	}
+}
+
+// Returns the new source, or nil if it doesn't parse or fails `verify`.
+func (self *fixer) apply(edits []srcedit.Edit, verify func([]byte) bool) (out []byte) {
+	err := try.Catch(func() { out = srcedit.Apply(self.src, edits, trytypes.Path) })
+	if err != nil || !verify(out) {
+		return nil
+	}
+	return out
+}
+
+/*
+True if any named result other than the error is written in the body, including
+closures, or has its address taken.
+*/
+func (self *fixer) assignsResults(body *ast.BlockStmt, sig *types.Signature) bool {
+	isOther := func(expr ast.Expr) bool {
+		ident, ok := ast.Unparen(expr).(*ast.Ident)
+		if !ok {
+			return false
+		}
+		val, _ := self.info.Uses[ident].(*types.Var)
+		return val != nil && isResult(sig, val) && !trytypes.IsError(val.Type())
+	}
+
+	found := false
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			for _, val := range node.Lhs {
+				found = found || isOther(val)
+			}
+		case *ast.IncDecStmt:
+			found = found || isOther(node.X)
+		case *ast.UnaryExpr:
+			found = found || (node.Op == token.AND && isOther(node.X))
+		case *ast.RangeStmt:
+			found = found || (node.Tok == token.ASSIGN && (isOther(node.Key) || isOther(node.Value)))
+		}
+		return !found
+	})
+	return found
+}
+
+func (self *fixer) object(ident *ast.Ident) types.Object {
+	if obj := self.info.Defs[ident]; obj != nil {
+		return obj
+	}
+	return self.info.Uses[ident]
+}
+
+func (self *fixer) offset(pos token.Pos) int {
+	return self.fset.Position(pos).Offset
+}
+
+func (self *fixer) text(node ast.Node) string {
+	return string(self.src[self.offset(node.Pos()):self.offset(node.End())])
+}
+
+func paramNamed(sig *types.Signature, name string) bool {
+	for ind := 0; ind < sig.Params().Len(); ind++ {
+		if sig.Params().At(ind).Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isResult(sig *types.Signature, val *types.Var) bool {
+	for ind := 0; ind < sig.Results().Len(); ind++ {
+		if sig.Results().At(ind) == val {
+			return true
+		}
+	}
+	return false
+}
+
+func isZero(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name == `nil` || expr.Name == `false`
+	case *ast.BasicLit:
+		return expr.Value == `0` || expr.Value == `0.0` || expr.Value == `""` || expr.Value == "``"
+	case *ast.CompositeLit:
+		return len(expr.Elts) == 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/mitranim/try/cmd/internal/trytypes"
+)
+
+func TestFixFile(t *testing.T) {
+	test := func(src, exp string) {
+		t.Helper()
+		out := fix(t, src)
+		if out != exp {
+			t.Fatalf("unexpected output:\n%v\nexpected:\n%v", out, exp)
+		}
+	}
+
+	test(`package main
+
+import "os"
+
+func someFunc() error {
+	err := os.Remove("one")
+	if err != nil {
+		return err
+	}
+	if err := os.Remove("two"); err != nil {
+		return err
+	}
+	return nil
+}
+`, `package main
+
+import (
+	"os"
+
+	"github.com/mitranim/try"
+)
+
+func someFunc() (err error) {
+	defer try.Rec(&err)
+	try.To(os.Remove("one"))
+	try.To(os.Remove("two"))
+	return nil
+}
+`)
+
+	test(`package main
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+func someFunc(path string) (int, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to X")
+	}
+	num, err := strconv.Atoi(string(body))
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to X")
+	}
+	return num, nil
+}
+`, `package main
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/mitranim/try"
+)
+
+func someFunc(path string) (_ int, err error) {
+	defer try.RecWithMessage(&err, "failed to X")
+	body := try.ByteSlice(os.ReadFile(path))
+	num := try.Int(strconv.Atoi(string(body)))
+	return num, nil
+}
+`)
+
+	// The check with a message is left as-is, and keeps declaring the variable
+	// used by the other check.
+	test(`package main
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+func someFunc() error {
+	err := os.Remove("one")
+	if err != nil {
+		return errors.WithMessage(err, "failed to remove one")
+	}
+	err = os.Remove("two")
+	if err != nil {
+		return err
+	}
+	return nil
+}
+`, ``)
+
+	test(`package main
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+func someFunc() error {
+	if err := os.Remove("one"); err != nil {
+		return errors.WithMessage(err, "failed to remove one")
+	}
+	if err := os.Remove("two"); err != nil {
+		return err
+	}
+	return nil
+}
+`, `package main
+
+import (
+	"os"
+
+	"github.com/mitranim/try"
+	"github.com/pkg/errors"
+)
+
+func someFunc() (err error) {
+	defer try.Rec(&err)
+	if err := os.Remove("one"); err != nil {
+		return errors.WithMessage(err, "failed to remove one")
+	}
+	try.To(os.Remove("two"))
+	return nil
+}
+`)
+}
+
+func TestFixFile_unsafe(t *testing.T) {
+	test := func(src string) {
+		t.Helper()
+		out := fix(t, src)
+		if out != `` {
+			t.Fatalf("expected no changes, got:\n%v", out)
+		}
+	}
+
+	// The error is used after the check.
+	test(`package main
+
+import "os"
+
+func someFunc() error {
+	err := os.Remove("one")
+	if err != nil {
+		return err
+	}
+	return err
+}
+`)
+
+	// Non-zero values are returned along with the error.
+	test(`package main
+
+import "strconv"
+
+func someFunc(src string) (int, error) {
+	num, err := strconv.Atoi(src)
+	if err != nil {
+		return -1, err
+	}
+	return num, nil
+}
+`)
+
+	// A named result is assigned before the check, and the explicit zero value
+	// of the return would be lost.
+	test(`package main
+
+import "strconv"
+
+func someFunc(src string) (num int, err error) {
+	num = 5
+	val, err := strconv.Atoi(src)
+	if err != nil {
+		return 0, err
+	}
+	return val, nil
+}
+`)
+
+	// The result would conflict with a package-level declaration named "try".
+	test(`package main
+
+import "os"
+
+var try = 0
+
+func someFunc() error {
+	err := os.Remove("one")
+	if err != nil {
+		return err
+	}
+	return nil
+}
+`)
+
+	// There's no typed "try" function for "*os.File".
+	test(`package main
+
+import "os"
+
+func someFunc() error {
+	file, err := os.Open("one")
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+`)
+}
+
+func fix(t *testing.T, src string) string {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, `main.go`, src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := &types.Info{
+		Types:  map[ast.Expr]types.TypeAndValue{},
+		Defs:   map[*ast.Ident]types.Object{},
+		Uses:   map[*ast.Ident]types.Object{},
+		Scopes: map[ast.Node]*types.Scope{},
+	}
+	imp := importer.ForCompiler(fset, `source`, nil)
+	conf := types.Config{Importer: imp}
+	_, err = conf.Check(`main`, fset, []*ast.File{file}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(fixFile(fset, file, info, []byte(src), func(out []byte) bool {
+		return trytypes.Compiles(fset, `main`, []*ast.File{file}, file, out, imp)
+	}))
+}
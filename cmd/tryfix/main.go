@@ -0,0 +1,103 @@
+/*
+Rewrites conventional Go error handling into the "try" style:
+
+	func someFunc() (err error) {
+		defer try.Rec(&err)
+		try.To(someFuncA())
+		val := try.String(someFuncB())
+		...
+	}
+
+Replaces checks such as `if err != nil { return err }` that follow a call, adds
+a named `err` result when missing, and inserts `defer try.Rec(&err)`. When all
+checks in a function wrap the error via `errors.WithMessage` with the same
+constant message, they're collapsed into one `defer try.RecWithMessage`. Code
+where the rewrite would change behavior or break compilation is left as-is; the
+module must already depend on package "try".
+
+Usage:
+
+	tryfix [-d] [packages]
+
+By default, rewrites files in place. With "-d", prints a diff instead, without
+modifying anything.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/types"
+	"os"
+
+	"github.com/mitranim/try"
+	"github.com/mitranim/try/cmd/internal/diff"
+	"github.com/mitranim/try/cmd/internal/trytypes"
+	"golang.org/x/tools/go/packages"
+)
+
+func main() {
+	defer try.RecWith(func(err error) {
+		fmt.Fprintf(os.Stderr, "tryfix: %v\n", err)
+		os.Exit(1)
+	})
+
+	dry := flag.Bool(`d`, false, `print diffs instead of rewriting files`)
+	flag.Parse()
+
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{`.`}
+	}
+
+	// With tests enabled, non-test files appear in several package variants.
+	seen := map[string]bool{}
+	pkgs := load(patterns)
+	var fallback types.Importer
+	if len(pkgs) > 0 {
+		fallback = importer.ForCompiler(pkgs[0].Fset, `source`, nil)
+	}
+
+	for _, pkg := range pkgs {
+		imp := trytypes.Importer{Pkg: pkg.Types, Fallback: fallback}
+
+		for ind, file := range pkg.Syntax {
+			path := pkg.CompiledGoFiles[ind]
+			if seen[path] || ast.IsGenerated(file) {
+				continue
+			}
+			seen[path] = true
+
+			src := try.ByteSlice(os.ReadFile(path))
+			out := fixFile(pkg.Fset, file, pkg.TypesInfo, src, func(out []byte) bool {
+				return trytypes.Compiles(pkg.Fset, pkg.PkgPath, pkg.Syntax, file, out, imp)
+			})
+			if out == nil {
+				continue
+			}
+
+			if *dry {
+				_, _ = os.Stdout.Write(diff.Unified(path, path, src, out))
+				continue
+			}
+			try.To(os.WriteFile(path, out, 0666))
+			fmt.Fprintln(os.Stderr, path)
+		}
+	}
+}
+
+func load(patterns []string) []*packages.Package {
+	conf := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+			packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Tests: true,
+	}
+	pkgs, err := packages.Load(conf, patterns...)
+	try.To(err)
+	if packages.PrintErrors(pkgs) > 0 {
+		panic(fmt.Errorf(`failed to load packages`))
+	}
+	return pkgs
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mitranim/try/cmd/internal/trytypes"
+)
+
+/*
+Generates the source of a package with panicking wrappers of the given
+functions and types of the source package. When the list of names is empty,
+wraps everything exported that has something to wrap. Panics if a name is
+missing or can't be wrapped.
+*/
+func generate(src *types.Package, pkgName string, names []string, args string) []byte {
+	gen := generator{
+		src:     src,
+		imports: map[string]string{},
+		taken:   map[string]bool{`try`: true},
+	}
+	gen.importName(src)
+
+	if len(names) == 0 {
+		names = gen.exported()
+	}
+	for _, name := range names {
+		gen.object(name)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by \"trygen %v\"; DO NOT EDIT.\n\n", args)
+	fmt.Fprintf(&out, "package %v\n\n", pkgName)
+	out.WriteString("import (\n")
+	for _, std := range []bool{true, false} {
+		for _, path := range gen.importPaths(std) {
+			name := gen.imports[path]
+			if name == defaultName(path) {
+				fmt.Fprintf(&out, "%q\n", path)
+			} else {
+				fmt.Fprintf(&out, "%v %q\n", name, path)
+			}
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString(")\n")
+	out.Write(gen.buf.Bytes())
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		panic(fmt.Errorf("failed to format generated code: %w\n%s", err, out.Bytes()))
+	}
+	return formatted
+}
+
+type generator struct {
+	src     *types.Package
+	buf     bytes.Buffer
+	imports map[string]string
+	taken   map[string]bool
+}
+
+// Exported functions and types with at least one wrappable function or method.
+func (self *generator) exported() []string {
+	var out []string
+	scope := self.src.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		switch obj := obj.(type) {
+		case *types.Func:
+			if self.wrappable(obj) {
+				out = append(out, name)
+			}
+		case *types.TypeName:
+			if !obj.IsAlias() && len(self.methods(obj)) > 0 {
+				out = append(out, name)
+			}
+		}
+	}
+	return out
+}
+
+func (self *generator) object(name string) {
+	obj := self.src.Scope().Lookup(name)
+	switch obj := obj.(type) {
+	case *types.Func:
+		if !self.wrappable(obj) {
+			panic(fmt.Errorf(`function %q doesn't return an error or can't be wrapped`, name))
+		}
+		self.function(obj)
+	case *types.TypeName:
+		methods := self.methods(obj)
+		if len(methods) == 0 {
+			panic(fmt.Errorf(`type %q has no methods returning an error`, name))
+		}
+		self.typ(obj, methods)
+	default:
+		panic(fmt.Errorf(`function or type %q not found in %q`, name, self.src.Path()))
+	}
+}
+
+func (self *generator) function(fun *types.Func) {
+	sig := fun.Type().(*types.Signature)
+	qual := self.importName(self.src) + `.` + fun.Name()
+
+	fmt.Fprintf(&self.buf, "\n// Panicking version of `%v`.\n", qual)
+	self.buf.WriteString(`func ` + fun.Name())
+	self.body(sig, qual, qual)
+}
+
+/*
+Wrappers of interfaces and non-pointer types embed the original value. Types
+with pointer methods are embedded by pointer. Methods without errors remain
+accessible through embedding.
+*/
+func (self *generator) typ(obj *types.TypeName, methods []*types.Func) {
+	typ := self.typeString(obj.Type())
+	name := `Try` + obj.Name()
+
+	embed := typ
+	if !types.IsInterface(obj.Type()) && !self.valueMethods(obj, methods) {
+		embed = `*` + typ
+	}
+
+	fmt.Fprintf(&self.buf, "\n// Panicking wrapper of `%v`.\n", typ)
+	fmt.Fprintf(&self.buf, "type %v struct{ %v }\n", name, embed)
+
+	for _, fun := range methods {
+		qual := typ + `.` + fun.Name()
+		fmt.Fprintf(&self.buf, "\n// Panicking version of `%v`.\n", qual)
+		fmt.Fprintf(&self.buf, "func (self %v) %v", name, fun.Name())
+		self.body(fun.Type().(*types.Signature), qual, `self.`+obj.Name()+`.`+fun.Name())
+	}
+}
+
+// Writes the signature without the error result, and the body.
+func (self *generator) body(sig *types.Signature, msg, callee string) {
+	params := sig.Params()
+	results := sig.Results()
+
+	paramTypes := make([]string, params.Len())
+	for ind := range paramTypes {
+		typ := params.At(ind).Type()
+		if sig.Variadic() && ind == params.Len()-1 {
+			paramTypes[ind] = `...` + self.typeString(typ.(*types.Slice).Elem())
+		} else {
+			paramTypes[ind] = self.typeString(typ)
+		}
+	}
+	resultTypes := make([]string, results.Len()-1)
+	for ind := range resultTypes {
+		resultTypes[ind] = self.typeString(results.At(ind).Type())
+	}
+
+	// Names are chosen after the types, to avoid shadowing the imports.
+	paramNames := make([]string, params.Len())
+	for ind := range paramNames {
+		paramNames[ind] = self.paramName(params.At(ind).Name(), ind, paramNames[:ind])
+	}
+	resultNames := make([]string, len(resultTypes))
+	for ind := range resultNames {
+		if len(resultNames) == 1 {
+			resultNames[ind] = `val`
+		} else {
+			resultNames[ind] = `val` + strconv.Itoa(ind)
+		}
+	}
+
+	self.buf.WriteString(`(`)
+	for ind := range paramNames {
+		if ind > 0 {
+			self.buf.WriteString(`, `)
+		}
+		self.buf.WriteString(paramNames[ind] + ` ` + paramTypes[ind])
+	}
+	self.buf.WriteString(`)`)
+	switch len(resultTypes) {
+	case 0:
+	case 1:
+		self.buf.WriteString(` ` + resultTypes[0])
+	default:
+		self.buf.WriteString(` (` + strings.Join(resultTypes, `, `) + `)`)
+	}
+	self.buf.WriteString(" {\n")
+
+	fmt.Fprintf(&self.buf, "defer try.Detail(%q)\n", msg)
+
+	call := callee + `(` + strings.Join(paramNames, `, `)
+	if sig.Variadic() {
+		call += `...`
+	}
+	call += `)`
+
+	switch len(resultTypes) {
+	case 0:
+		fmt.Fprintf(&self.buf, "try.To(%v)\n", call)
+	case 1:
+		if fun := trytypes.TryFunc(results.At(0).Type()); fun != `` {
+			fmt.Fprintf(&self.buf, "return try.%v(%v)\n", fun, call)
+			break
+		}
+		fallthrough
+	default:
+		fmt.Fprintf(&self.buf, "%v, err := %v\n", strings.Join(resultNames, `, `), call)
+		self.buf.WriteString("try.To(err)\n")
+		fmt.Fprintf(&self.buf, "return %v\n", strings.Join(resultNames, `, `))
+	}
+	self.buf.WriteString("}\n")
+}
+
+// Names used for generated parameters and results.
+var reGenerated = regexp.MustCompile(`^(?:val|arg)\d*$`)
+
+func (self *generator) paramName(name string, ind int, prev []string) string {
+	reserved := name == `` || name == `_` || name == `self` || name == `err` ||
+		reGenerated.MatchString(name) ||
+		self.taken[name] || token.IsKeyword(name)
+	for _, val := range prev {
+		reserved = reserved || val == name
+	}
+	if reserved {
+		return `arg` + strconv.Itoa(ind)
+	}
+	return name
+}
+
+/*
+Exported methods returning an error as the last result, in the order of their
+names, excluding `stdMethods`. For non-interface types, includes methods of the
+pointer type.
+*/
+func (self *generator) methods(obj *types.TypeName) []*types.Func {
+	typ := obj.Type()
+	if _, ok := typ.(*types.Named); !ok || obj.Type().(*types.Named).TypeParams().Len() > 0 {
+		return nil
+	}
+	if !types.IsInterface(typ) {
+		typ = types.NewPointer(typ)
+	}
+
+	var out []*types.Func
+	set := types.NewMethodSet(typ)
+	for ind := 0; ind < set.Len(); ind++ {
+		fun := set.At(ind).Obj().(*types.Func)
+		if fun.Exported() && !stdMethods[fun.Name()] && self.wrappable(fun) {
+			out = append(out, fun)
+		}
+	}
+	sort.Slice(out, func(one, two int) bool { return out[one].Name() < out[two].Name() })
+	return out
+}
+
+/*
+Methods with conventional signatures checked by "go vet", such as `io.Seeker`.
+Their wrappers would violate the conventions, and `Unwrap` doesn't return
+failures in the first place.
+*/
+var stdMethods = map[string]bool{
+	`As`:            true,
+	`Format`:        true,
+	`GobDecode`:     true,
+	`GobEncode`:     true,
+	`Is`:            true,
+	`MarshalJSON`:   true,
+	`MarshalXML`:    true,
+	`Peek`:          true,
+	`ReadByte`:      true,
+	`ReadFrom`:      true,
+	`ReadRune`:      true,
+	`Scan`:          true,
+	`Seek`:          true,
+	`UnmarshalJSON`: true,
+	`UnmarshalXML`:  true,
+	`UnreadByte`:    true,
+	`UnreadRune`:    true,
+	`Unwrap`:        true,
+	`WriteByte`:     true,
+	`WriteTo`:       true,
+}
+
+func (self *generator) valueMethods(obj *types.TypeName, methods []*types.Func) bool {
+	set := types.NewMethodSet(obj.Type())
+	for _, fun := range methods {
+		if set.Lookup(fun.Pkg(), fun.Name()) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+True if the function returns an error as the last result, isn't generic, and
+its signature references only exported types.
+*/
+func (self *generator) wrappable(fun *types.Func) bool {
+	sig := fun.Type().(*types.Signature)
+	res := sig.Results()
+	if sig.TypeParams().Len() > 0 || res.Len() == 0 || !trytypes.IsError(res.At(res.Len()-1).Type()) {
+		return false
+	}
+	for _, tuple := range []*types.Tuple{sig.Params(), res} {
+		for ind := 0; ind < tuple.Len(); ind++ {
+			if !exportedType(tuple.At(ind).Type()) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (self *generator) typeString(typ types.Type) string {
+	return types.TypeString(typ, self.importName)
+}
+
+// Name under which the package is imported, unique within the generated file.
+func (self *generator) importName(pkg *types.Package) string {
+	name, ok := self.imports[pkg.Path()]
+	if ok {
+		return name
+	}
+	name = pkg.Name()
+	for ind := 1; self.taken[name]; ind++ {
+		name = pkg.Name() + strconv.Itoa(ind)
+	}
+	self.taken[name] = true
+	self.imports[pkg.Path()] = name
+	return name
+}
+
+// Paths of either standard or other imports, including package "try".
+func (self *generator) importPaths(std bool) []string {
+	var out []string
+	if !std {
+		out = append(out, trytypes.Path)
+	}
+	for path := range self.imports {
+		if isStd(path) == std {
+			out = append(out, path)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Standard library paths don't have a dot in the first segment.
+func isStd(path string) bool {
+	head, _, _ := strings.Cut(path, `/`)
+	return !strings.Contains(head, `.`)
+}
+
+func defaultName(path string) string {
+	return path[strings.LastIndexByte(path, '/')+1:]
+}
+
+// False if the type references any unexported named type of another package.
+func exportedType(typ types.Type) bool {
+	switch typ := typ.(type) {
+	case *types.Named:
+		obj := typ.Obj()
+		if obj.Pkg() != nil && !obj.Exported() {
+			return false
+		}
+		args := typ.TypeArgs()
+		for ind := 0; ind < args.Len(); ind++ {
+			if !exportedType(args.At(ind)) {
+				return false
+			}
+		}
+		return true
+	case *types.Pointer:
+		return exportedType(typ.Elem())
+	case *types.Slice:
+		return exportedType(typ.Elem())
+	case *types.Array:
+		return exportedType(typ.Elem())
+	case *types.Map:
+		return exportedType(typ.Key()) && exportedType(typ.Elem())
+	case *types.Chan:
+		return exportedType(typ.Elem())
+	case *types.Signature:
+		for _, tuple := range []*types.Tuple{typ.Params(), typ.Results()} {
+			for ind := 0; ind < tuple.Len(); ind++ {
+				if !exportedType(tuple.At(ind).Type()) {
+					return false
+				}
+			}
+		}
+		return true
+	case *types.Struct:
+		for ind := 0; ind < typ.NumFields(); ind++ {
+			if !typ.Field(ind).Exported() || !exportedType(typ.Field(ind).Type()) {
+				return false
+			}
+		}
+		return true
+	default:
+		return true
+	}
+}
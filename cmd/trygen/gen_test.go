@@ -0,0 +1,132 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+const testSrc = `package store
+
+import (
+	"context"
+	"io"
+)
+
+type User struct{ ID string }
+
+type Store interface {
+	Get(ctx context.Context, id string) (User, error)
+	Count() (int, error)
+	Close() error
+	Name() string
+}
+
+type Client struct{}
+
+func (self *Client) Read(out io.Writer, ids ...string) (int64, bool, error) { return 0, false, nil }
+
+func (self *Client) Unwrappable() (unexported, error) { return unexported{}, nil }
+
+type unexported struct{}
+
+func Open(store string) (*Client, error) { return nil, nil }
+
+func Version() string { return "" }
+`
+
+func TestGenerate(t *testing.T) {
+	out := string(generate(checkSrc(t, testSrc), `trystore`, nil, `-pkg example.com/store`))
+	exp := "// Code generated by \"trygen -pkg example.com/store\"; DO NOT EDIT.\n" + `
+package trystore
+
+import (
+	"context"
+	"io"
+
+	"example.com/store"
+	"github.com/mitranim/try"
+)
+
+// Panicking wrapper of ` + "`store.Client`" + `.
+type TryClient struct{ *store.Client }
+
+// Panicking version of ` + "`store.Client.Read`" + `.
+func (self TryClient) Read(out io.Writer, ids ...string) (int64, bool) {
+	defer try.Detail("store.Client.Read")
+	val0, val1, err := self.Client.Read(out, ids...)
+	try.To(err)
+	return val0, val1
+}
+
+// Panicking version of ` + "`store.Open`" + `.
+func Open(arg0 string) *store.Client {
+	defer try.Detail("store.Open")
+	val, err := store.Open(arg0)
+	try.To(err)
+	return val
+}
+
+// Panicking wrapper of ` + "`store.Store`" + `.
+type TryStore struct{ store.Store }
+
+// Panicking version of ` + "`store.Store.Close`" + `.
+func (self TryStore) Close() {
+	defer try.Detail("store.Store.Close")
+	try.To(self.Store.Close())
+}
+
+// Panicking version of ` + "`store.Store.Count`" + `.
+func (self TryStore) Count() int {
+	defer try.Detail("store.Store.Count")
+	return try.Int(self.Store.Count())
+}
+
+// Panicking version of ` + "`store.Store.Get`" + `.
+func (self TryStore) Get(ctx context.Context, id string) store.User {
+	defer try.Detail("store.Store.Get")
+	val, err := self.Store.Get(ctx, id)
+	try.To(err)
+	return val
+}
+`
+	if out != exp {
+		t.Fatalf("unexpected output:\n%v\nexpected:\n%v", out, exp)
+	}
+}
+
+func TestGenerate_missing(t *testing.T) {
+	test := func(name string) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("expected a panic for %q", name)
+			}
+		}()
+		generate(checkSrc(t, testSrc), `trystore`, []string{name}, ``)
+	}
+
+	test(`Missing`)
+	test(`Version`)
+	test(`User`)
+}
+
+func checkSrc(t *testing.T, src string) *types.Package {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, `store.go`, src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, `source`, nil)}
+	pkg, err := conf.Check(`example.com/store`, fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
@@ -0,0 +1,95 @@
+/*
+Generates panicking wrappers of functions and methods that return errors, for
+use in the "exceptions" style. For example, for this package:
+
+	package store
+
+	type Store interface {
+		Get(id string) (User, error)
+	}
+
+Running "trygen -pkg example.com/store Store" generates:
+
+	// Panicking wrapper of `store.Store`.
+	type TryStore struct{ store.Store }
+
+	// Panicking version of `store.Store.Get`.
+	func (self TryStore) Get(id string) store.User {
+		defer try.Detail("store.Store.Get")
+		val, err := self.Store.Get(id)
+		try.To(err)
+		return val
+	}
+
+Each wrapper passes the error to `try.To`, or to a typed "try" function such as
+`try.String` when available, and adds the name of the wrapped function or
+method to panics via `try.Detail`. Types are wrapped by embedding, which keeps
+other methods accessible. Functions are wrapped under the same name.
+
+Usage:
+
+	trygen -pkg <path> [-name <name>] [-out <file>] [names]
+
+When no names are given, wraps all exported functions returning an error, and
+all exported types with methods returning an error. Generic functions and types
+are not supported. Methods referencing unexported types are skipped, and so are
+methods with conventional signatures checked by "go vet", such as `Seek`. The
+generated package is named "try" followed by the name of the source package,
+unless specified via "-name". Writes to stdout unless "-out" is specified.
+Suitable for "go:generate" directives:
+
+	//go:generate go run github.com/mitranim/try/cmd/trygen@latest -pkg example.com/store -out store.go
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/types"
+	"os"
+	"strings"
+
+	"github.com/mitranim/try"
+	"golang.org/x/tools/go/packages"
+)
+
+func main() {
+	defer try.RecWith(func(err error) {
+		fmt.Fprintf(os.Stderr, "trygen: %v\n", err)
+		os.Exit(1)
+	})
+
+	path := flag.String(`pkg`, ``, `import path of the package to wrap`)
+	name := flag.String(`name`, ``, `name of the generated package`)
+	out := flag.String(`out`, ``, `output file; defaults to stdout`)
+	flag.Parse()
+
+	if *path == `` {
+		panic(fmt.Errorf(`missing "-pkg"`))
+	}
+
+	src := load(*path)
+	if *name == `` {
+		*name = `try` + src.Name()
+	}
+
+	body := generate(src, *name, flag.Args(), strings.Join(os.Args[1:], ` `))
+	if *out == `` {
+		_, _ = os.Stdout.Write(body)
+		return
+	}
+	try.To(os.WriteFile(*out, body, 0666))
+}
+
+func load(path string) *types.Package {
+	conf := &packages.Config{Mode: packages.NeedName | packages.NeedTypes}
+	pkgs, err := packages.Load(conf, path)
+	try.To(err)
+	if packages.PrintErrors(pkgs) > 0 {
+		panic(fmt.Errorf(`failed to load package %q`, path))
+	}
+	if len(pkgs) != 1 {
+		panic(fmt.Errorf(`expected exactly one package for %q, found %v`, path, len(pkgs)))
+	}
+	return pkgs[0].Types
+}
@@ -0,0 +1,81 @@
+/*
+Converts Go crash output into JSON or a human-readable format. Accepts Go
+runtime panics and goroutine dumps, and errors from "github.com/pkg/errors"
+printed via `%+v`. See `try.ParsePanic`.
+
+Usage:
+
+	trystack [flags] [file]
+
+Reads from stdin when the file is omitted.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mitranim/try"
+)
+
+func main() {
+	defer try.RecWith(func(err error) {
+		fmt.Fprintf(os.Stderr, "trystack: %v\n", err)
+		os.Exit(1)
+	})
+
+	asJSON := flag.Bool(`json`, false, `output JSON`)
+	opt := try.FormatOpt{}
+	flag.BoolVar(&opt.Compact, `compact`, false, `print the error in one line`)
+	flag.BoolVar(&opt.Color, `color`, false, `use ANSI colors`)
+	flag.IntVar(&opt.Source, `source`, 0, `amount of source lines around each frame`)
+	flag.BoolVar(&opt.SkipInternal, `skip-internal`, false, `omit runtime and standard library frames`)
+	flag.StringVar(&opt.Root, `root`, ``, `print paths relative to this directory`)
+	flag.Parse()
+
+	src := input()
+	defer src.Close()
+	dump := try.ParsePanic(src)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent(``, `  `)
+		try.To(enc.Encode(dump))
+		return
+	}
+
+	if dump.Err != nil {
+		fmt.Println(try.Format(dump.Err, opt))
+	}
+
+	// The first goroutine is the one that failed, already printed above.
+	for ind, val := range dump.Goroutines {
+		if ind == 0 && dump.Err != nil {
+			continue
+		}
+		fmt.Println()
+		fmt.Println(try.Format(goroutineErr(val), opt))
+	}
+}
+
+func input() io.ReadCloser {
+	switch flag.NArg() {
+	case 0:
+		return io.NopCloser(os.Stdin)
+	case 1:
+		return try.Interface(os.Open(flag.Arg(0))).(*os.File)
+	default:
+		panic(fmt.Errorf(`expected at most one file, got %v`, flag.NArg()))
+	}
+}
+
+func goroutineErr(val try.Goroutine) *try.RemoteError {
+	return &try.RemoteError{
+		Type:  `goroutine`,
+		Msg:   fmt.Sprintf(`%v [%v]`, val.ID, val.State),
+		Stack: val.Stack,
+	}
+}
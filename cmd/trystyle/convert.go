@@ -0,0 +1,760 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/mitranim/try/cmd/internal/srcedit"
+	"github.com/mitranim/try/cmd/internal/trytypes"
+)
+
+// Type-checked source file, as loaded by "go/packages".
+type unit struct {
+	path string
+	fset *token.FileSet
+	file *ast.File
+	pkg  *types.Package
+	info *types.Info
+	src  []byte
+}
+
+/*
+Converts the target function, identified by `types.Func.FullName`, and all its
+callers in the given files. When `toTry` is true, the function gains an error
+result and recovers its panics; otherwise it loses its error result and panics
+instead. Returns the new source of each modified file, keyed by path. Panics
+with a list of positions if any part of the conversion is unsafe, without
+producing partial results.
+*/
+func convert(units []unit, target string, toTry bool) map[string][]byte {
+	conv := converter{target: target, toTry: toTry, ifaces: interfaces(units)}
+	var files []*fileConv
+
+	for _, unit := range units {
+		file := &fileConv{
+			converter: &conv,
+			unit:      unit,
+			pkgName:   srcedit.ImportName(unit.file, trytypes.Path, `try`),
+		}
+		file.convert()
+		files = append(files, file)
+	}
+
+	if conv.found == 0 {
+		panic(fmt.Errorf(`function %q not found`, target))
+	}
+	if len(conv.fails) > 0 {
+		panic(fmt.Errorf("unable to convert %q:\n%v", target, strings.Join(conv.fails, "\n")))
+	}
+
+	out := map[string][]byte{}
+	for _, file := range files {
+		if len(file.edits) > 0 {
+			out[file.path] = srcedit.Apply(file.src, file.edits, append(file.imports, trytypes.Path)...)
+		}
+	}
+	return out
+}
+
+type converter struct {
+	target string
+	toTry  bool
+	ifaces []iface
+	found  int
+	fails  []string
+}
+
+// Interface type known to the converted packages, with a readable name.
+type iface struct {
+	name string
+	typ  *types.Interface
+}
+
+type fileConv struct {
+	*converter
+	unit
+	pkgName string
+	edits   []srcedit.Edit
+	imports []string
+}
+
+func (self *fileConv) convert() {
+	for _, decl := range self.file.Decls {
+		decl, ok := decl.(*ast.FuncDecl)
+		if ok && decl.Body != nil && self.isTarget(self.info.Defs[decl.Name]) {
+			self.found++
+			self.checkInterfaces(decl)
+			if self.toTry {
+				self.targetToTry(decl)
+			} else {
+				self.targetToExceptions(decl)
+			}
+		}
+	}
+	self.callers()
+}
+
+func (self *fileConv) isTarget(obj types.Object) bool {
+	fun, _ := obj.(*types.Func)
+	return fun != nil && fun.Origin().FullName() == self.target
+}
+
+/*
+Changing the signature of a method breaks its conversion to any interface it
+satisfies. Since such conversions may be implicit, any interface known to the
+converted packages, which the receiver type satisfies, prevents the conversion.
+For generic receivers, a method of the same name is enough.
+*/
+func (self *fileConv) checkInterfaces(decl *ast.FuncDecl) {
+	fun := self.info.Defs[decl.Name].(*types.Func)
+	recv := fun.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return
+	}
+
+	typ := recv.Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, _ := typ.(*types.Named)
+	generic := named != nil && named.TypeParams().Len() > 0
+
+	for _, val := range self.ifaces {
+		obj, _, _ := types.LookupFieldOrMethod(val.typ, false, nil, fun.Name())
+		if obj == nil {
+			continue
+		}
+		if generic || types.Implements(typ, val.typ) || types.Implements(types.NewPointer(typ), val.typ) {
+			self.fail(decl.Name, `the method satisfies %v`, val.name)
+			return
+		}
+	}
+}
+
+func (self *fileConv) fail(node interface{ Pos() token.Pos }, msg string, args ...interface{}) {
+	self.fails = append(self.fails, fmt.Sprintf(`%v: %v`, self.fset.Position(node.Pos()), fmt.Sprintf(msg, args...)))
+}
+
+/*
+Converts a function such as:
+
+	func someFunc() (_ string, err error) {
+		defer try.Rec(&err)
+		return "val", nil
+	}
+
+Into:
+
+	func someFunc() string {
+		return "val"
+	}
+
+Deferred `Rec` and `RecOnly` are removed, and `RecWithMessage` and
+`WithMessage` become `Detail`. Errors returned explicitly are passed to `To`,
+unless the function defers `RecWithMessage`, which doesn't add its message to
+them.
+*/
+func (self *fileConv) targetToExceptions(decl *ast.FuncDecl) {
+	sig := self.info.Defs[decl.Name].Type().(*types.Signature)
+	if sig.Results().Len() == 0 || !trytypes.IsError(sig.Results().At(sig.Results().Len()-1).Type()) {
+		self.fail(decl.Name, `expected the last result to be an error`)
+		return
+	}
+	errVar := sig.Results().At(sig.Results().Len() - 1)
+	handled := map[*ast.Ident]bool{}
+	bare := false
+
+	// Unlike `Detail`, the "rec" functions don't add the message to returned
+	// errors, which would gain it after the conversion.
+	recMsg := false
+	for _, stmt := range decl.Body.List {
+		recMsg = self.deferToExceptions(stmt, errVar, handled) || recMsg
+	}
+
+	self.returns(decl.Body, func(ret *ast.ReturnStmt) {
+		if len(ret.Results) == 0 {
+			bare = true
+			return
+		}
+		if len(ret.Results) != sig.Results().Len() {
+			self.fail(ret, `unable to split a multi-value return`)
+			return
+		}
+
+		last := ret.Results[len(ret.Results)-1]
+		var buf strings.Builder
+		if ident, ok := last.(*ast.Ident); ok && self.info.Uses[ident] == errVar {
+			handled[ident] = true
+		} else if !self.isNil(last) {
+			if recMsg {
+				self.fail(last, `the returned error would gain the message of "defer %v.Detail"`, self.pkgName)
+			}
+			buf.WriteString(self.pkgName + `.To(` + self.text(last) + ")\n")
+		}
+
+		buf.WriteString(`return`)
+		for ind, val := range ret.Results[:len(ret.Results)-1] {
+			if ind == 0 {
+				buf.WriteString(` `)
+			} else {
+				buf.WriteString(`, `)
+			}
+			buf.WriteString(self.text(val))
+		}
+		self.replace(ret, buf.String())
+	})
+
+	self.checkUses(decl.Body, errVar, handled)
+	self.replaceResults(decl.Type, self.resultsWithoutError(decl.Type, bare))
+}
+
+// Returns true if the deferral was a "rec" function with a message, which
+// applies only to panics.
+func (self *fileConv) deferToExceptions(stmt ast.Stmt, errVar *types.Var, handled map[*ast.Ident]bool) bool {
+	def, ok := stmt.(*ast.DeferStmt)
+	if !ok || len(def.Call.Args) == 0 {
+		return false
+	}
+	ident := self.errPtr(def.Call.Args[0], errVar)
+	if ident == nil {
+		return false
+	}
+
+	var name string
+	tryName := self.tryName(def.Call.Fun)
+	switch tryName {
+	case `Rec`, `RecOnly`:
+		handled[ident] = true
+		start, end := srcedit.Lines(self.src, self.offset(def.Pos()), self.offset(def.End()))
+		self.edits = append(self.edits, srcedit.Edit{Start: start, End: end})
+		return false
+	case `RecWithMessage`, `WithMessage`:
+		name = `Detail`
+	case `RecWithMessagef`, `WithMessagef`:
+		name = `Detailf`
+	default:
+		return false
+	}
+
+	handled[ident] = true
+	self.replace(def, `defer `+self.pkgName+`.`+name+`(`+self.args(def.Call, 1)+`)`)
+	return strings.HasPrefix(tryName, `Rec`)
+}
+
+// Returns the identifier in `&err` if it refers to the given variable.
+func (self *fileConv) errPtr(expr ast.Expr, errVar *types.Var) *ast.Ident {
+	unary, ok := expr.(*ast.UnaryExpr)
+	if !ok || unary.Op != token.AND {
+		return nil
+	}
+	ident, ok := unary.X.(*ast.Ident)
+	if !ok || self.info.Uses[ident] != errVar {
+		return nil
+	}
+	return ident
+}
+
+func (self *fileConv) checkUses(body *ast.BlockStmt, errVar *types.Var, handled map[*ast.Ident]bool) {
+	ast.Inspect(body, func(node ast.Node) bool {
+		ident, ok := node.(*ast.Ident)
+		if ok && self.info.Uses[ident] == errVar && !handled[ident] {
+			self.fail(ident, `the error result is used outside of "defer %v.Rec" and returns`, self.pkgName)
+		}
+		return true
+	})
+}
+
+/*
+Result list without the trailing error. Names consisting only of "_" are
+dropped, unless bare returns depend on the results being named.
+*/
+func (self *fileConv) resultsWithoutError(typ *ast.FuncType, bare bool) string {
+	var names, typs []string
+	for _, field := range typ.Results.List {
+		if len(field.Names) == 0 {
+			names = append(names, ``)
+			typs = append(typs, self.text(field.Type))
+			continue
+		}
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+			typs = append(typs, self.text(field.Type))
+		}
+	}
+	names, typs = names[:len(names)-1], typs[:len(typs)-1]
+
+	named := false
+	for _, name := range names {
+		if name != `` && (name != `_` || bare) {
+			named = true
+		}
+	}
+
+	if !named {
+		if len(typs) == 1 {
+			return typs[0]
+		}
+		if len(typs) == 0 {
+			return ``
+		}
+		return `(` + strings.Join(typs, `, `) + `)`
+	}
+
+	var buf strings.Builder
+	buf.WriteString(`(`)
+	for ind := range names {
+		if ind > 0 {
+			buf.WriteString(`, `)
+		}
+		buf.WriteString(names[ind] + ` ` + typs[ind])
+	}
+	buf.WriteString(`)`)
+	return buf.String()
+}
+
+/*
+Converts a function such as:
+
+	func someFunc() string {
+		defer try.Detail("failed to X")
+		return "val"
+	}
+
+Into:
+
+	func someFunc() (_ string, err error) {
+		defer try.RecWithMessage(&err, "failed to X")
+		return "val", nil
+	}
+*/
+func (self *fileConv) targetToTry(decl *ast.FuncDecl) {
+	sig := self.info.Defs[decl.Name].Type().(*types.Signature)
+	res := sig.Results()
+	if res.Len() > 0 && trytypes.IsError(res.At(res.Len()-1).Type()) {
+		self.fail(decl.Name, `the last result is already an error`)
+		return
+	}
+
+	if obj := self.info.Scopes[decl.Type].Lookup(`err`); obj != nil {
+		self.fail(obj, `"err" is already declared`)
+		return
+	}
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.Ident:
+			if node.Name == `err` && self.info.Defs[node] != nil {
+				self.fail(node, `"err" would shadow the error result`)
+			}
+		}
+		return true
+	})
+
+	rec := `defer ` + self.pkgName + `.Rec(&err)`
+	var detail *ast.DeferStmt
+	if len(decl.Body.List) > 0 {
+		def, ok := decl.Body.List[0].(*ast.DeferStmt)
+		if ok {
+			switch self.tryName(def.Call.Fun) {
+			case `Detail`:
+				detail = def
+				rec = `defer ` + self.pkgName + `.RecWithMessage(&err, ` + self.args(def.Call, 0) + `)`
+			case `Detailf`:
+				detail = def
+				rec = `defer ` + self.pkgName + `.RecWithMessagef(&err, ` + self.args(def.Call, 0) + `)`
+			}
+		}
+	}
+
+	if detail != nil {
+		self.replace(detail, rec)
+	} else {
+		pos := self.offset(decl.Body.Lbrace) + 1
+		self.edits = append(self.edits, srcedit.Edit{Start: pos, End: pos, Text: "\n" + rec})
+	}
+
+	self.returns(decl.Body, func(ret *ast.ReturnStmt) {
+		if len(ret.Results) == 0 {
+			return
+		}
+		if len(ret.Results) != res.Len() {
+			self.fail(ret, `unable to extend a multi-value return`)
+			return
+		}
+		pos := self.offset(ret.Results[len(ret.Results)-1].End())
+		self.edits = append(self.edits, srcedit.Edit{Start: pos, End: pos, Text: `, nil`})
+	})
+
+	self.replaceResults(decl.Type, self.resultsWithError(decl.Type))
+}
+
+func (self *fileConv) resultsWithError(typ *ast.FuncType) string {
+	if typ.Results == nil || len(typ.Results.List) == 0 {
+		return `(err error)`
+	}
+	if len(typ.Results.List[0].Names) > 0 {
+		return `(` + self.span(typ.Results.List[0], typ.Results.List[len(typ.Results.List)-1]) + `, err error)`
+	}
+
+	var buf strings.Builder
+	buf.WriteString(`(`)
+	for _, field := range typ.Results.List {
+		buf.WriteString(`_ ` + self.text(field.Type) + `, `)
+	}
+	buf.WriteString(`err error)`)
+	return buf.String()
+}
+
+func (self *fileConv) replaceResults(typ *ast.FuncType, text string) {
+	start := self.offset(typ.Params.End())
+	end := start
+	if typ.Results != nil {
+		end = self.offset(typ.Results.End())
+	}
+	if text != `` {
+		text = ` ` + text
+	}
+	self.edits = append(self.edits, srcedit.Edit{Start: start, End: end, Text: text})
+}
+
+// Calls the function for each return statement of the body, excluding closures.
+func (self *fileConv) returns(body *ast.BlockStmt, fun func(*ast.ReturnStmt)) {
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			fun(node)
+		}
+		return true
+	})
+}
+
+/*
+Updates all calls of the target in the file. Calls which already pass the
+result to `To` or a typed "try" function are unwrapped, and other calls are
+adapted via closures that keep the original result types.
+*/
+func (self *fileConv) callers() {
+	var stack []ast.Node
+	var owner *ast.FuncDecl
+
+	ast.Inspect(self.file, func(node ast.Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, node)
+
+		if decl, ok := node.(*ast.FuncDecl); ok {
+			owner = decl
+		}
+
+		ident, ok := node.(*ast.Ident)
+		if !ok || !self.isTarget(self.info.Uses[ident]) {
+			return true
+		}
+		if owner != nil && self.isTarget(self.info.Defs[owner.Name]) {
+			self.fail(ident, `recursive calls are not supported`)
+			return true
+		}
+		self.caller(ident, stack)
+		return true
+	})
+}
+
+func (self *fileConv) caller(ident *ast.Ident, stack []ast.Node) {
+	// Walk up from the identifier to the call: "someFunc", "pkg.someFunc",
+	// "val.someMethod", "someFunc[int]".
+	ind := len(stack) - 1
+	var expr ast.Node = ident
+	for ind > 0 {
+		switch parent := stack[ind-1].(type) {
+		case *ast.SelectorExpr:
+			if parent.Sel != expr {
+				break
+			}
+			expr = parent
+			ind--
+			continue
+		case *ast.IndexExpr:
+			if parent.X != expr {
+				break
+			}
+			expr = parent
+			ind--
+			continue
+		case *ast.IndexListExpr:
+			if parent.X != expr {
+				break
+			}
+			expr = parent
+			ind--
+			continue
+		}
+		break
+	}
+
+	if ind == 0 {
+		self.fail(ident, `used as a value rather than called`)
+		return
+	}
+	call, ok := stack[ind-1].(*ast.CallExpr)
+	if !ok || call.Fun != expr {
+		self.fail(ident, `used as a value rather than called`)
+		return
+	}
+	if self.overlaps(call) {
+		self.fail(call, `nested calls are not supported`)
+		return
+	}
+
+	var parent ast.Node
+	if ind > 1 {
+		parent = stack[ind-2]
+	}
+	switch parent.(type) {
+	case *ast.GoStmt, *ast.DeferStmt:
+		self.fail(call, `unable to convert a call in a "go" or "defer" statement`)
+		return
+	}
+
+	outs := results(self.info.TypeOf(call))
+	if self.toTry {
+		self.callerToTry(call, outs)
+	} else {
+		self.callerToExceptions(call, parent, outs)
+	}
+}
+
+func (self *fileConv) callerToExceptions(call *ast.CallExpr, parent ast.Node, outs []types.Type) {
+	if wrap, ok := parent.(*ast.CallExpr); ok && len(wrap.Args) == 1 && self.isPanicking(wrap.Fun) {
+		self.replace(wrap, self.text(call))
+		return
+	}
+
+	outs = outs[:len(outs)-1]
+	if len(outs) == 0 {
+		self.replace(call, self.pkgName+`.Catch(func() { `+self.text(call)+` })`)
+		return
+	}
+
+	names, errName := self.adapterNames(call, len(outs))
+	var buf strings.Builder
+	buf.WriteString(`func() (`)
+	for ind, typ := range outs {
+		buf.WriteString(names[ind] + ` ` + self.typeText(typ) + `, `)
+	}
+	buf.WriteString(errName + " error) {\n")
+	buf.WriteString(`defer ` + self.pkgName + `.Rec(&` + errName + ")\n")
+	buf.WriteString(strings.Join(names, `, `) + ` = ` + self.text(call) + "\n")
+	buf.WriteString("return\n}()")
+	self.replace(call, buf.String())
+}
+
+func (self *fileConv) callerToTry(call *ast.CallExpr, outs []types.Type) {
+	if len(outs) == 0 {
+		self.replace(call, self.pkgName+`.To(`+self.text(call)+`)`)
+		return
+	}
+	if len(outs) == 1 {
+		if fun := trytypes.TryFunc(outs[0]); fun != `` {
+			self.replace(call, self.pkgName+`.`+fun+`(`+self.text(call)+`)`)
+			return
+		}
+	}
+
+	names, errName := self.adapterNames(call, len(outs))
+	var buf strings.Builder
+	buf.WriteString(`func() (`)
+	for ind, typ := range outs {
+		if ind > 0 {
+			buf.WriteString(`, `)
+		}
+		buf.WriteString(names[ind] + ` ` + self.typeText(typ))
+	}
+	buf.WriteString(") {\n")
+	buf.WriteString(`var ` + errName + " error\n")
+	buf.WriteString(strings.Join(append(names, errName), `, `) + ` = ` + self.text(call) + "\n")
+	buf.WriteString(self.pkgName + `.To(` + errName + ")\n")
+	buf.WriteString("return\n}()")
+	self.replace(call, buf.String())
+}
+
+// Names of adapter results which don't shadow anything used by the call.
+func (self *fileConv) adapterNames(call *ast.CallExpr, count int) ([]string, string) {
+	used := map[string]bool{self.pkgName: true}
+	ast.Inspect(call, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Ident); ok {
+			used[ident.Name] = true
+		}
+		return true
+	})
+
+	free := func(base string) string {
+		name := base
+		for ind := 1; used[name]; ind++ {
+			name = base + strconv.Itoa(ind)
+		}
+		used[name] = true
+		return name
+	}
+
+	var names []string
+	for ind := 0; ind < count; ind++ {
+		if count == 1 {
+			names = append(names, free(`val`))
+		} else {
+			names = append(names, free(`val`+strconv.Itoa(ind)))
+		}
+	}
+	return names, free(`err`)
+}
+
+// Type in the syntax of the current file, adding imports when needed.
+func (self *fileConv) typeText(typ types.Type) string {
+	return types.TypeString(typ, func(pkg *types.Package) string {
+		if pkg == self.pkg {
+			return ``
+		}
+		self.imports = append(self.imports, pkg.Path())
+		return srcedit.ImportName(self.file, pkg.Path(), pkg.Name())
+	})
+}
+
+func (self *fileConv) overlaps(node ast.Node) bool {
+	start, end := self.offset(node.Pos()), self.offset(node.End())
+	for _, val := range self.edits {
+		if val.Start < end && start < val.End {
+			return true
+		}
+	}
+	return false
+}
+
+// Name of the function of package "try" referenced by the expression, if any.
+func (self *fileConv) tryName(expr ast.Expr) string {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return ``
+	}
+	obj := self.info.Uses[sel.Sel]
+	if obj == nil || !trytypes.IsFunc(obj, obj.Name()) {
+		return ``
+	}
+	return obj.Name()
+}
+
+func (self *fileConv) isPanicking(expr ast.Expr) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	fun, _ := self.info.Uses[sel.Sel].(*types.Func)
+	return trytypes.IsPanicking(fun)
+}
+
+func (self *fileConv) isNil(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && self.info.Uses[ident] == types.Universe.Lookup(`nil`)
+}
+
+// Source text of the call arguments, starting at the given index.
+func (self *fileConv) args(call *ast.CallExpr, from int) string {
+	if from >= len(call.Args) {
+		return ``
+	}
+	out := self.span(call.Args[from], call.Args[len(call.Args)-1])
+	if call.Ellipsis.IsValid() {
+		out += `...`
+	}
+	return out
+}
+
+func (self *fileConv) replace(node ast.Node, text string) {
+	self.edits = append(self.edits, srcedit.Edit{
+		Start: self.offset(node.Pos()),
+		End:   self.offset(node.End()),
+		Text:  text,
+	})
+}
+
+func (self *fileConv) offset(pos token.Pos) int {
+	return self.fset.Position(pos).Offset
+}
+
+func (self *fileConv) text(node ast.Node) string {
+	return self.span(node, node)
+}
+
+// Source text from the start of one node to the end of another.
+func (self *fileConv) span(start, end ast.Node) string {
+	return string(self.src[self.offset(start.Pos()):self.offset(end.End())])
+}
+
+/*
+Interfaces declared in the given packages and the packages they import, and
+interface literals used by the given files.
+*/
+func interfaces(units []unit) []iface {
+	var out []iface
+	seen := map[types.Type]bool{}
+
+	add := func(name string, typ types.Type) {
+		val, ok := typ.Underlying().(*types.Interface)
+		if !ok || val.NumMethods() == 0 || seen[typ] {
+			return
+		}
+		seen[typ] = true
+		out = append(out, iface{name, val})
+	}
+
+	addScope := func(pkg *types.Package) {
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			obj, ok := scope.Lookup(name).(*types.TypeName)
+			if ok {
+				add(pkg.Path()+`.`+name, obj.Type())
+			}
+		}
+	}
+
+	for _, unit := range units {
+		if unit.pkg == nil {
+			continue
+		}
+		addScope(unit.pkg)
+		for _, pkg := range unit.pkg.Imports() {
+			addScope(pkg)
+		}
+	}
+
+	for _, unit := range units {
+		for _, val := range unit.info.Types {
+			if _, ok := val.Type.(*types.Interface); ok {
+				add(val.Type.String(), val.Type)
+			}
+		}
+	}
+	return out
+}
+
+func results(typ types.Type) []types.Type {
+	tuple, ok := typ.(*types.Tuple)
+	if !ok {
+		if typ == nil {
+			return nil
+		}
+		return []types.Type{typ}
+	}
+	out := make([]types.Type, tuple.Len())
+	for ind := range out {
+		out[ind] = tuple.At(ind).Type()
+	}
+	return out
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+func TestConvert_toExceptions(t *testing.T) {
+	test := func(src, exp string) {
+		t.Helper()
+		out := convertSrc(t, src, `main.someFunc`, false)
+		if out != exp {
+			t.Fatalf("unexpected output:\n%v\nexpected:\n%v", out, exp)
+		}
+	}
+
+	test(`package main
+
+import (
+	"os"
+
+	"github.com/mitranim/try"
+)
+
+func someFunc(path string) (_ []byte, err error) {
+	defer try.WithMessage(&err, "failed to read")
+	body := try.ByteSlice(os.ReadFile(path))
+	if len(body) == 0 {
+		return nil, os.ErrNotExist
+	}
+	return body, nil
+}
+
+func main() {
+	body := try.ByteSlice(someFunc("one"))
+	_ = body
+
+	val, err := someFunc("two")
+	_, _ = val, err
+}
+`, `package main
+
+import (
+	"os"
+
+	"github.com/mitranim/try"
+)
+
+func someFunc(path string) []byte {
+	defer try.Detail("failed to read")
+	body := try.ByteSlice(os.ReadFile(path))
+	if len(body) == 0 {
+		try.To(os.ErrNotExist)
+		return nil
+	}
+	return body
+}
+
+func main() {
+	body := someFunc("one")
+	_ = body
+
+	val, err := func() (val []byte, err error) {
+		defer try.Rec(&err)
+		val = someFunc("two")
+		return
+	}()
+	_, _ = val, err
+}
+`)
+
+	test(`package main
+
+import "github.com/mitranim/try"
+
+func someFunc() (err error) {
+	defer try.Rec(&err)
+	return nil
+}
+
+func main() {
+	try.To(someFunc())
+	err := someFunc()
+	_ = err
+}
+`, `package main
+
+import "github.com/mitranim/try"
+
+func someFunc() {
+	return
+}
+
+func main() {
+	someFunc()
+	err := try.Catch(func() { someFunc() })
+	_ = err
+}
+`)
+}
+
+func TestConvert_toTry(t *testing.T) {
+	test := func(src, exp string) {
+		t.Helper()
+		out := convertSrc(t, src, `main.someFunc`, true)
+		if out != exp {
+			t.Fatalf("unexpected output:\n%v\nexpected:\n%v", out, exp)
+		}
+	}
+
+	test(`package main
+
+import "github.com/mitranim/try"
+
+type Val struct{}
+
+func someFunc(src string) (Val, int) {
+	defer try.Detailf("failed to parse %q", src)
+	return Val{}, len(src)
+}
+
+func main() {
+	val, num := someFunc("one")
+	_, _ = val, num
+}
+`, `package main
+
+import "github.com/mitranim/try"
+
+type Val struct{}
+
+func someFunc(src string) (_ Val, _ int, err error) {
+	defer try.RecWithMessagef(&err, "failed to parse %q", src)
+	return Val{}, len(src), nil
+}
+
+func main() {
+	val, num := func() (val0 Val, val1 int) {
+		var err error
+		val0, val1, err = someFunc("one")
+		try.To(err)
+		return
+	}()
+	_, _ = val, num
+}
+`)
+
+	test(`package main
+
+func someFunc(src string) string {
+	return src
+}
+
+func main() {
+	someFunc("one")
+	println(someFunc("two"))
+}
+`, `package main
+
+import "github.com/mitranim/try"
+
+func someFunc(src string) (_ string, err error) {
+	defer try.Rec(&err)
+	return src, nil
+}
+
+func main() {
+	try.String(someFunc("one"))
+	println(try.String(someFunc("two")))
+}
+`)
+}
+
+func TestConvert_unsafe(t *testing.T) {
+	test := func(src, target string, toTry bool, exp string) {
+		t.Helper()
+		msg := func() (msg string) {
+			defer func() { msg = fmt.Sprint(recover()) }()
+			convertSrc(t, src, target, toTry)
+			return
+		}()
+		if !strings.Contains(msg, exp) {
+			t.Fatalf("expected a panic containing %q, got %q", exp, msg)
+		}
+	}
+
+	test(`package main
+
+func someFunc() (err error) {
+	err = nil
+	return err
+}
+`, `main.someFunc`, false, `the error result is used`)
+
+	test(`package main
+
+func someFunc() {}
+
+func main() {
+	fun := someFunc
+	fun()
+}
+`, `main.someFunc`, true, `used as a value`)
+
+	test(`package main
+
+func someFunc(num int) int {
+	if num > 0 {
+		return someFunc(num - 1)
+	}
+	return num
+}
+`, `main.someFunc`, true, `recursive calls`)
+
+	test(`package main
+
+func someFunc() {}
+
+func main() {
+	defer someFunc()
+}
+`, `main.someFunc`, true, `"go" or "defer"`)
+
+	test(`package main
+
+import (
+	"errors"
+
+	"github.com/mitranim/try"
+)
+
+func someFunc() (_ string, err error) {
+	defer try.RecWithMessage(&err, "failed")
+	return "", errors.New("bad")
+}
+`, `main.someFunc`, false, `would gain the message`)
+
+	test(`package main
+
+import "fmt"
+
+type Val struct{}
+
+func (Val) String() string { return "" }
+
+func main() { fmt.Println(Val{}) }
+`, `(main.Val).String`, true, `the method satisfies fmt.Stringer`)
+
+	test(`package main
+
+type Namer interface{ Name() string }
+
+type Val struct{}
+
+func (*Val) Name() string { return "" }
+`, `(*main.Val).Name`, true, `the method satisfies main.Namer`)
+
+	test(`package main
+
+func someFunc() {}
+`, `main.otherFunc`, true, `not found`)
+}
+
+func convertSrc(t *testing.T, src, target string, toTry bool) string {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, `main.go`, src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := &types.Info{
+		Types:  map[ast.Expr]types.TypeAndValue{},
+		Defs:   map[*ast.Ident]types.Object{},
+		Uses:   map[*ast.Ident]types.Object{},
+		Scopes: map[ast.Node]*types.Scope{},
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, `source`, nil)}
+	pkg, err := conf.Check(`main`, fset, []*ast.File{file}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := convert([]unit{{
+		path: `main.go`,
+		fset: fset,
+		file: file,
+		pkg:  pkg,
+		info: info,
+		src:  []byte(src),
+	}}, target, toTry)
+	return string(out[`main.go`])
+}
@@ -0,0 +1,135 @@
+/*
+Converts a function between the "try" style and the "exceptions" style, updating
+its callers accordingly.
+
+Converting to the exceptions style turns:
+
+	func someFunc() (_ string, err error) {
+		defer try.Rec(&err)
+		return "val", nil
+	}
+
+Into:
+
+	func someFunc() string {
+		return "val"
+	}
+
+Callers which pass the result to `try.To` or a typed "try" function, such as
+`try.String(someFunc())`, are unwrapped. Other callers, which handle the error
+themselves, get an adapter that recovers the panic via `try.Catch` or
+`try.Rec`, preserving their behavior.
+
+Converting to the try style does the opposite: the function gains a named `err`
+result and `defer try.Rec(&err)`, with `defer try.Detail` becoming
+`defer try.RecWithMessage`, and its callers are wrapped in `try.To` or a typed
+"try" function, so they keep panicking.
+
+Usage:
+
+	trystyle -to exceptions|try -func <name> [-d] [packages]
+
+The function name has the format of `types.Func.FullName`, for example
+"example.com/pkg.SomeFunc" or "(*example.com/pkg.SomeType).SomeMethod". Only
+callers in the given packages are updated; by default, the packages of the
+current module.
+
+Nothing is modified when any part of the conversion is unsafe, such as uses of
+the function as a value, recursive calls, uses of the error result other than
+`defer try.Rec(&err)` and returns, methods satisfying an interface known to the
+given packages, or errors returned explicitly by a function which defers
+`try.RecWithMessage`, since `try.Detail` would add its message to them. By default, rewrites files in place.
+With "-d", prints a diff instead, without modifying anything.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"os"
+	"sort"
+
+	"github.com/mitranim/try"
+	"github.com/mitranim/try/cmd/internal/diff"
+	"golang.org/x/tools/go/packages"
+)
+
+func main() {
+	defer try.RecWith(func(err error) {
+		fmt.Fprintf(os.Stderr, "trystyle: %v\n", err)
+		os.Exit(1)
+	})
+
+	to := flag.String(`to`, ``, `target style: "exceptions" or "try"`)
+	fun := flag.String(`func`, ``, `full name of the function to convert`)
+	dry := flag.Bool(`d`, false, `print diffs instead of rewriting files`)
+	flag.Parse()
+
+	if *to != `exceptions` && *to != `try` {
+		panic(fmt.Errorf(`expected "-to exceptions" or "-to try", got %q`, *to))
+	}
+	if *fun == `` {
+		panic(fmt.Errorf(`missing "-func"`))
+	}
+
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{`./...`}
+	}
+
+	out := convert(load(patterns), *fun, *to == `try`)
+
+	var paths []string
+	for path := range out {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if *dry {
+			src := try.ByteSlice(os.ReadFile(path))
+			_, _ = os.Stdout.Write(diff.Unified(path, path, src, out[path]))
+			continue
+		}
+		try.To(os.WriteFile(path, out[path], 0666))
+		fmt.Fprintln(os.Stderr, path)
+	}
+}
+
+// With tests enabled, non-test files appear in several package variants, and
+// are included only once.
+func load(patterns []string) []unit {
+	conf := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+			packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Tests: true,
+	}
+	pkgs, err := packages.Load(conf, patterns...)
+	try.To(err)
+	if packages.PrintErrors(pkgs) > 0 {
+		panic(fmt.Errorf(`failed to load packages`))
+	}
+
+	var out []unit
+	seen := map[string]bool{}
+	for _, pkg := range pkgs {
+		for ind, file := range pkg.Syntax {
+			path := pkg.CompiledGoFiles[ind]
+			if seen[path] || ast.IsGenerated(file) {
+				continue
+			}
+			seen[path] = true
+
+			out = append(out, unit{
+				path: path,
+				fset: pkg.Fset,
+				file: file,
+				pkg:  pkg.Types,
+				info: pkg.TypesInfo,
+				src:  try.ByteSlice(os.ReadFile(path)),
+			})
+		}
+	}
+	return out
+}
@@ -0,0 +1,22 @@
+/*
+Reports misuse of "github.com/mitranim/try". Runs all analyzers from
+"github.com/mitranim/try/tryvet". Install:
+
+	go install github.com/mitranim/try/cmd/tryvet@latest
+
+Can be used standalone:
+
+	tryvet ./...
+
+Or as a vet tool:
+
+	go vet -vettool=$(which tryvet) ./...
+*/
+package main
+
+import (
+	"github.com/mitranim/try/tryvet"
+	"golang.org/x/tools/go/analysis/multichecker"
+)
+
+func main() { multichecker.Main(tryvet.Analyzers...) }
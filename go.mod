@@ -1,5 +1,5 @@
 module github.com/mitranim/try
 
-go 1.15
+go 1.20
 
 require github.com/pkg/errors v0.9.1
@@ -0,0 +1,85 @@
+package try_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mitranim/try"
+)
+
+type benchVal struct {
+	ID   int
+	Name string
+	Tags []string
+}
+
+var benchInput = benchVal{ID: 123, Name: `name`, Tags: []string{`one`, `two`}}
+
+func BenchmarkDetail(b *testing.B) {
+	b.ReportAllocs()
+	for ind := 0; ind < b.N; ind++ {
+		benchDetail()
+	}
+}
+
+func benchDetail() {
+	defer try.Detail(`failed to process`)
+}
+
+func BenchmarkDetailf(b *testing.B) {
+	b.ReportAllocs()
+	for ind := 0; ind < b.N; ind++ {
+		benchDetailf(benchInput)
+	}
+}
+
+func benchDetailf(val benchVal) {
+	defer try.Detailf(`failed to process %v`, val)
+}
+
+func BenchmarkDetailFunc(b *testing.B) {
+	b.ReportAllocs()
+	for ind := 0; ind < b.N; ind++ {
+		benchDetailFunc(benchInput)
+	}
+}
+
+func benchDetailFunc(val benchVal) {
+	defer try.DetailFunc(func() string { return fmt.Sprintf(`failed to process %v`, val) })
+}
+
+func BenchmarkRecWithMessagef(b *testing.B) {
+	b.ReportAllocs()
+	for ind := 0; ind < b.N; ind++ {
+		_ = benchRecWithMessagef(benchInput)
+	}
+}
+
+func benchRecWithMessagef(val benchVal) (err error) {
+	defer try.RecWithMessagef(&err, `failed to process %v`, val)
+	return
+}
+
+func BenchmarkRecWithMessageFunc(b *testing.B) {
+	b.ReportAllocs()
+	for ind := 0; ind < b.N; ind++ {
+		_ = benchRecWithMessageFunc(benchInput)
+	}
+}
+
+func benchRecWithMessageFunc(val benchVal) (err error) {
+	defer try.RecWithMessageFunc(&err, func() string { return fmt.Sprintf(`failed to process %v`, val) })
+	return
+}
+
+func TestDetailFunc_noAlloc(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() { benchDetailFunc(benchInput) })
+	if allocs != 0 {
+		t.Fatalf(`expected DetailFunc to not allocate on success, got %v allocations`, allocs)
+	}
+
+	allocs = testing.AllocsPerRun(100, func() { _ = benchRecWithMessageFunc(benchInput) })
+	if allocs != 0 {
+		t.Fatalf(`expected RecWithMessageFunc to not allocate on success, got %v allocations`, allocs)
+	}
+}
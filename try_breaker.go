@@ -0,0 +1,219 @@
+package try
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+Matches errors of `Breaker.Run` when the circuit is open, via `errors.Is`. Use
+`errors.As` with `*OpenError` to get the details.
+*/
+var ErrOpen error = &OpenError{}
+
+/*
+Error of `Breaker.Run` when the circuit is open. Retryable according to
+`IsRetryable`, because the breaker eventually lets calls through again.
+`Until` is when the circuit becomes half-open, and is zero when it's already
+half-open, with a trial call in progress.
+*/
+type OpenError struct{ Until time.Time }
+
+// Implement `error`.
+func (*OpenError) Error() string { return `circuit breaker is open` }
+
+// Implement the interface used by `IsRetryable`.
+func (*OpenError) Retryable() bool { return true }
+
+// Implement `errors.Is`. Matches any `*OpenError`, including `ErrOpen`.
+func (*OpenError) Is(target error) bool {
+	_, ok := target.(*OpenError)
+	return ok
+}
+
+// State of a `Breaker`.
+type BreakerState uint8
+
+const (
+	// Calls go through, and failures are counted.
+	BreakerClosed BreakerState = iota
+
+	// Calls fail fast with `*OpenError`.
+	BreakerOpen
+
+	// One trial call goes through, and decides whether to close the circuit or
+	// open it again. Other calls fail fast with `*OpenError`.
+	BreakerHalfOpen
+)
+
+// Lowercase name such as "half-open".
+func (self BreakerState) String() string {
+	switch self {
+	case BreakerClosed:
+		return `closed`
+	case BreakerOpen:
+		return `open`
+	case BreakerHalfOpen:
+		return `half-open`
+	default:
+		return ``
+	}
+}
+
+/*
+Circuit breaker for calls in the "exceptions" style, which stops calling a
+failing dependency for a while. Safe for concurrent use. The zero value is
+ready to use. Must not be copied after first use. Usage:
+
+	var breaker = &try.Breaker{Threshold: 5, Timeout: 10 * time.Second}
+
+	breaker.Run(func() { callDownstream() })
+
+`Threshold` is the amount of consecutive failures which opens the circuit,
+defaulting to 5. `Timeout` is how long the circuit stays open before becoming
+half-open, defaulting to 10 seconds.
+
+`Test` decides which failures are counted, like the test in `CatchOnly`. Nil
+means all errors are counted. Errors not satisfying the test, for example
+"not found" errors, indicate that the dependency is healthy, and count as
+successes.
+
+`Now` is the clock which decides when the open circuit becomes half-open,
+defaulting to `time.Now`. The breaker doesn't use timers, so a fake clock fully
+controls the timeout, without waiting.
+*/
+type Breaker struct {
+	Threshold int
+	Timeout   time.Duration
+	Test      func(error) bool
+	Now       func() time.Time
+
+	lock       sync.Mutex
+	state      BreakerState
+	generation uint64
+	failures   int
+	opened     time.Time
+	probing    bool
+}
+
+/*
+Runs the function through the breaker. Panics with `*OpenError`, which matches
+`ErrOpen`, without calling the function, if the circuit is open, or half-open
+with a trial call already in progress. Otherwise calls the function, records the outcome, and re-panics its
+error, if any. Panics are converted to errors via `Catch`.
+*/
+func (self *Breaker) Run(fun func()) {
+	gen, probe := self.before()
+
+	finished := false
+	var err error
+	defer func() { self.after(gen, probe, finished, err) }()
+
+	err = Catch(fun)
+	finished = true
+	To(err)
+}
+
+// Current state of the breaker.
+func (self *Breaker) State() BreakerState {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.current()
+}
+
+// Must be called under lock.
+func (self *Breaker) current() BreakerState {
+	if self.state == BreakerOpen && !self.now().Before(self.until()) {
+		self.setState(BreakerHalfOpen)
+	}
+	return self.state
+}
+
+/*
+Must be called under lock. Every change of the state starts a new generation.
+Outcomes of calls started in earlier generations are ignored, because they say
+nothing about the dependency since then.
+*/
+func (self *Breaker) setState(state BreakerState) {
+	if state == self.state {
+		return
+	}
+	self.state = state
+	self.generation++
+	self.failures = 0
+	self.probing = false
+	if state == BreakerOpen {
+		self.opened = self.now()
+	}
+}
+
+// Must be called under lock.
+func (self *Breaker) until() time.Time { return self.opened.Add(self.timeout()) }
+
+func (self *Breaker) before() (uint64, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	switch self.current() {
+	case BreakerOpen:
+		To(&OpenError{Until: self.until()})
+	case BreakerHalfOpen:
+		if self.probing {
+			To(&OpenError{})
+		}
+		self.probing = true
+		return self.generation, true
+	}
+	return self.generation, false
+}
+
+/*
+If the function didn't finish, for example due to `runtime.Goexit`, the outcome
+is unknown, and the state doesn't change, other than allowing another trial.
+*/
+func (self *Breaker) after(gen uint64, probe, finished bool, err error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if gen != self.generation {
+		return
+	}
+	if probe {
+		self.probing = false
+	}
+	if !finished {
+		return
+	}
+
+	if err == nil || (self.Test != nil && !self.Test(err)) {
+		self.setState(BreakerClosed)
+		self.failures = 0
+		return
+	}
+
+	self.failures++
+	if probe || self.failures >= self.threshold() {
+		self.setState(BreakerOpen)
+	}
+}
+
+func (self *Breaker) threshold() int {
+	if self.Threshold > 0 {
+		return self.Threshold
+	}
+	return 5
+}
+
+func (self *Breaker) timeout() time.Duration {
+	if self.Timeout > 0 {
+		return self.Timeout
+	}
+	return 10 * time.Second
+}
+
+func (self *Breaker) now() time.Time {
+	if self.Now != nil {
+		return self.Now()
+	}
+	return time.Now()
+}
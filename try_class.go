@@ -0,0 +1,148 @@
+package try
+
+import "github.com/pkg/errors"
+
+// Severity of an error, for loggers and reporters. See `Class`.
+type Severity uint8
+
+const (
+	SeverityDebug Severity = iota + 1
+	SeverityInfo
+	SeverityWarn
+	SeverityError
+	SeverityFatal
+)
+
+// Lowercase name such as "warn". Returns "" for the zero value.
+func (self Severity) String() string {
+	switch self {
+	case SeverityDebug:
+		return `debug`
+	case SeverityInfo:
+		return `info`
+	case SeverityWarn:
+		return `warn`
+	case SeverityError:
+		return `error`
+	case SeverityFatal:
+		return `fatal`
+	default:
+		return ``
+	}
+}
+
+/*
+Classification of an error, attached via `Classify` or `Classified`, and
+queried via `ClassOf`. Zero fields are unspecified.
+
+`Code` is the exit code used by `Main`. `Severity` is used by loggers and
+reporters, such as "trysentry". `UserFacing` means that the error message is
+meant for end users, and can be shown to them even for server errors, for
+example by "tryhttp".
+*/
+type Class struct {
+	Code       int
+	Severity   Severity
+	UserFacing bool
+}
+
+/*
+Must be deferred. Attaches the classification to non-nil panics, idempotently
+adding a stacktrace. Doesn't change the error message. Usage:
+
+	defer try.Classify(try.Class{Code: 2, UserFacing: true})
+*/
+func Classify(class Class) {
+	To(Classified(Err(recover()), class))
+}
+
+/*
+Must be deferred. Attaches the classification to non-nil panics, but only if
+the error satisfies the provided test. Idempotently adds a stacktrace.
+*/
+func ClassifyOnly(test func(error) bool, class Class) {
+	err := Err(recover())
+	if err != nil && test != nil && test(err) {
+		err = Classified(err, class)
+	}
+	To(err)
+}
+
+/*
+Wraps the error, attaching the classification without changing the message.
+Returns nil for nil. The wrapper is elided by `Links`, and preserves the
+formatting of the wrapped error, including `%+v`.
+*/
+func Classified(err error, class Class) error {
+	if err == nil {
+		return nil
+	}
+	return &classError{transparentOf(err), class}
+}
+
+/*
+Returns the classification of the error. Each field is taken from the
+outermost layer of the error chain which specifies it; in other words, outer
+classifications override inner ones. Errors implementing `ExitCoder` with a
+positive code count as specifying `Class.Code`.
+*/
+func ClassOf(err error) (out Class) {
+	for err != nil {
+		switch val := err.(type) {
+		case *classError:
+			if out.Code == 0 {
+				out.Code = val.class.Code
+			}
+			if out.Severity == 0 {
+				out.Severity = val.class.Severity
+			}
+			out.UserFacing = out.UserFacing || val.class.UserFacing
+		case ExitCoder:
+			if out.Code == 0 && val.ExitCode() > 0 {
+				out.Code = val.ExitCode()
+			}
+		}
+
+		cause := errors.Unwrap(err)
+		if cause == err {
+			break
+		}
+		err = cause
+	}
+	return
+}
+
+// Exit code of the error: as specified by `ClassOf`, or 1. Returns 0 for nil.
+func ExitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	code := ClassOf(err).Code
+	if code > 0 {
+		return code
+	}
+	return 1
+}
+
+/*
+Severity of the error: as specified by `ClassOf`, or `SeverityError`. Returns
+0 for nil.
+*/
+func SeverityOf(err error) Severity {
+	if err == nil {
+		return 0
+	}
+	sev := ClassOf(err).Severity
+	if sev > 0 {
+		return sev
+	}
+	return SeverityError
+}
+
+// True if any layer of the error chain is classified as user-facing.
+func IsUserFacing(err error) bool { return ClassOf(err).UserFacing }
+
+type classError struct {
+	transparent
+	class Class
+}
@@ -57,10 +57,26 @@ func Detail(msg string) {
 
 /*
 Must be deferred. Wraps non-nil panics, prepending the error message and
-idempotently adding a stacktrace.
+idempotently adding a stacktrace. Arguments marked via `Secret` are redacted.
 */
 func Detailf(msg string, args ...interface{}) {
-	To(errors.WithMessagef(Err(recover()), msg, args...))
+	To(withMessagef(Err(recover()), msg, args...))
+}
+
+/*
+Must be deferred. Version of `Detail` which builds the message lazily, by
+calling the provided function only if there's an ongoing panic. Avoids the cost
+of formatting on the happy path; unlike `Detailf`, doesn't allocate when there's
+no panic, as long as the closure doesn't escape. Usage:
+
+	defer try.DetailFunc(func() string { return fmt.Sprintf(`failed to process %v`, val) })
+*/
+func DetailFunc(fun func() string) {
+	err := Err(recover())
+	if err != nil {
+		err = errors.WithMessage(err, fun())
+	}
+	To(err)
 }
 
 /*
@@ -78,11 +94,12 @@ func DetailOnly(test func(error) bool, msg string) {
 /*
 Must be deferred. Wraps non-nil panics, prepending the error message, ONLY if
 they satisfy the provided test. Idempotently adds a stacktrace to all panics.
+Arguments marked via `Secret` are redacted.
 */
 func DetailOnlyf(test func(error) bool, msg string, args ...interface{}) {
 	err := Err(recover())
 	if err != nil && test != nil && test(err) {
-		err = errors.WithMessagef(err, msg, args...)
+		err = withMessagef(err, msg, args...)
 	}
 	To(err)
 }
@@ -175,12 +192,25 @@ func RecWithMessage(ptr *error, msg string) {
 
 /*
 Must be deferred. Combination of `Rec` and `WithMessagef`. Recovers from panics
-and adds a message. Idempotently adds a stacktrace.
+and adds a message. Idempotently adds a stacktrace. Arguments marked via
+`Secret` are redacted.
 */
 func RecWithMessagef(ptr *error, pattern string, args ...interface{}) {
 	err := Err(recover())
 	if err != nil {
-		*ptr = errors.WithMessagef(err, pattern, args...)
+		*ptr = withMessagef(err, pattern, args...)
+	}
+}
+
+/*
+Must be deferred. Version of `RecWithMessage` which builds the message lazily,
+by calling the provided function only if there's an ongoing panic. See
+`DetailFunc`.
+*/
+func RecWithMessageFunc(ptr *error, fun func() string) {
+	err := Err(recover())
+	if err != nil {
+		*ptr = errors.WithMessage(err, fun())
 	}
 }
 
@@ -196,10 +226,11 @@ func WithMessage(ptr *error, msg string) {
 
 /*
 Must be deferred. Wraps a non-nil error, prepending the message. Unlike
-`RecWithMessagef`, does NOT implicitly recover or add a stacktrace.
+`RecWithMessagef`, does NOT implicitly recover or add a stacktrace. Arguments
+marked via `Secret` are redacted.
 */
 func WithMessagef(ptr *error, pattern string, args ...interface{}) {
 	if ptr != nil && *ptr != nil {
-		*ptr = errors.WithMessagef(*ptr, pattern, args...)
+		*ptr = withMessagef(*ptr, pattern, args...)
 	}
 }
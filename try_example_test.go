@@ -1,9 +1,15 @@
 package try_test
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/mitranim/try"
 	"github.com/pkg/errors"
@@ -663,3 +669,634 @@ func ExampleUintptrSlice() {
 	// Output:
 	// [255]
 }
+
+func ExampleStackOf() {
+	err := try.Catch(func() { panic(`failure`) })
+	frame := try.StackOf(err)[0]
+	fmt.Println(frame.Pkg(), frame.Name(), frame.IsInternal())
+	// Output:
+	// github.com/mitranim/try Err true
+}
+
+func ExampleFrame_IsInternal() {
+	for _, fun := range []string{
+		`runtime.gopanic`,
+		`encoding/json.Unmarshal`,
+		`github.com/mitranim/try.To`,
+		`github.com/mitranim/try/tryhttp.Client.Do`,
+		`github.com/mitranim/try/tryhttp_test.ExampleClient`,
+		`github.com/mitranim/trylike.Run`,
+		`main.main`,
+	} {
+		fmt.Println(fun, try.Frame{Func: fun}.IsInternal())
+	}
+	// Output:
+	// runtime.gopanic true
+	// encoding/json.Unmarshal true
+	// github.com/mitranim/try.To true
+	// github.com/mitranim/try/tryhttp.Client.Do true
+	// github.com/mitranim/try/tryhttp_test.ExampleClient false
+	// github.com/mitranim/trylike.Run false
+	// main.main false
+}
+
+func ExampleLinks() {
+	err := try.Catch(func() {
+		defer try.Detail(`failed to X`)
+		try.To(os.ErrNotExist)
+	})
+
+	for _, link := range try.Links(err) {
+		fmt.Printf("%v: %v (stack: %v)\n", link.Type, link.Msg, len(link.Stack) > 0)
+	}
+	// Output:
+	// *errors.withMessage: failed to X (stack: false)
+	// *errors.errorString: file does not exist (stack: true)
+}
+
+func ExampleFingerprint() {
+	fail := func(id int) error {
+		return try.Catch(func() {
+			defer try.Detailf(`failed to process %v`, id)
+			try.To(os.ErrNotExist)
+		})
+	}
+
+	fmt.Println(try.Fingerprint(fail(1)) == try.Fingerprint(fail(2)))
+	fmt.Println(try.Fingerprint(fail(1)) == try.Fingerprint(errors.New(`other`)))
+	// Output:
+	// true
+	// false
+}
+
+func ExampleDedup() {
+	logErr := try.Dedup(time.Minute, func(err error) {
+		fmt.Println(`caught:`, err)
+	})
+
+	for ind := 0; ind < 3; ind++ {
+		func() {
+			defer try.RecWith(logErr)
+			try.To(os.ErrNotExist)
+		}()
+	}
+	// Output:
+	// caught: file does not exist
+}
+
+func ExampleDedupWith() {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	opt := try.DedupOpt{
+		Interval: time.Minute,
+		Now:      func() time.Time { return now },
+	}
+	logErr := try.DedupWith(opt, func(err error) { fmt.Println(`caught:`, err) })
+
+	logErr(os.ErrNotExist)
+	logErr(os.ErrNotExist)
+
+	now = now.Add(time.Minute)
+	logErr(os.ErrNotExist)
+	// Output:
+	// caught: file does not exist
+	// caught: file does not exist
+}
+
+func ExampleFormat() {
+	err := try.Catch(func() {
+		defer try.Detail(`failed to X`)
+		panic(`failure`)
+	})
+
+	out := try.Format(err, try.FormatOpt{
+		Root:         try.ModuleRoot(),
+		SkipInternal: true,
+		Frames:       1,
+	})
+	fmt.Println(regexp.MustCompile(`:\d+`).ReplaceAllString(out, `:N`))
+	// Output:
+	// *errors.withMessage: failed to X
+	// Caused by: string: failure
+	// 	at github.com/mitranim/try_test.ExampleFormat.func1 (try_example_test.go:N)
+}
+
+func ExampleFormat_compact() {
+	err := try.Catch(func() { try.To(os.ErrNotExist) })
+	out := try.Format(err, try.FormatOpt{Compact: true, SkipInternal: true, Frames: 1})
+	fmt.Println(regexp.MustCompile(`:\d+`).ReplaceAllString(out, `:N`))
+	// Output:
+	// file does not exist at try_example_test.go:N
+}
+
+func ExampleMarshalError() {
+	try.RegisterSentinel(os.ErrNotExist)
+
+	src := try.Catch(func() {
+		defer try.Detail(`failed to X`)
+		try.To(os.ErrNotExist)
+	})
+
+	err := try.UnmarshalError(try.MarshalError(src))
+	fmt.Println(err)
+	fmt.Println(try.HasStack(err))
+	fmt.Println(errors.Is(err, os.ErrNotExist))
+	fmt.Println(try.StackOf(err)[0] == try.StackOf(src)[0])
+
+	// Panicking the remote error keeps the remote stacktrace. Explicitly added
+	// local stacktraces don't show up as separate layers.
+	fmt.Println(try.Catch(func() { try.To(err) }) == err)
+	for _, link := range try.Links(errors.WithStack(err)) {
+		fmt.Printf("%v: %q %v\n", link.Type, link.Msg, len(link.Stack) > 0)
+	}
+	// Output:
+	// failed to X: file does not exist
+	// true
+	// true
+	// true
+	// true
+	// *errors.withMessage: "failed to X" false
+	// *errors.errorString: "file does not exist" true
+}
+
+func ExampleParsePanic() {
+	const src = `panic: failure
+
+goroutine 1 [running]:
+main.someFunc(...)
+	/app/main.go:12
+main.main()
+	/app/main.go:20 +0x25
+
+goroutine 6 [chan receive]:
+main.worker()
+	/app/worker.go:8 +0x31
+created by main.main in goroutine 1
+	/app/main.go:18 +0x1d
+exit status 2
+`
+
+	dump := try.ParsePanic(strings.NewReader(src))
+	fmt.Println(dump.Err)
+	fmt.Println(try.StackOf(dump.Err)[0].Func)
+
+	for _, val := range dump.Goroutines {
+		fmt.Println(val.ID, val.State, len(val.Stack), val.CreatedBy != nil)
+	}
+	// Output:
+	// failure
+	// main.someFunc
+	// 1 running 2 false
+	// 6 chan receive 1 true
+}
+
+func ExampleParsePanic_repanicked() {
+	const src = `panic: first failure [recovered, repanicked]
+	panic: second failure
+
+goroutine 1 [running]:
+main.main()
+	/app/main.go:20 +0x25
+exit status 2
+`
+
+	dump := try.ParsePanic(strings.NewReader(src))
+	for _, link := range try.Links(dump.Err) {
+		fmt.Printf("%q\n", link.Msg)
+	}
+	// Output:
+	// "second failure"
+	// "first failure"
+}
+
+func ExampleParsePanic_errors() {
+	const src = `file does not exist
+main.readConfig
+	/app/config.go:14
+main.main
+	/app/main.go:20
+failed to read config
+`
+
+	dump := try.ParsePanic(strings.NewReader(src))
+	fmt.Println(dump.Err)
+	fmt.Println(try.Format(dump.Err, try.FormatOpt{Root: `/app`}))
+	// Output:
+	// failed to read config: file does not exist
+	// failed to read config
+	// Caused by: file does not exist
+	// 	at main.readConfig (config.go:14)
+	// 	at main.main (main.go:20)
+}
+
+type exitError struct{ code int }
+
+func (self exitError) Error() string { return `exit ` + strconv.Itoa(self.code) }
+func (self exitError) ExitCode() int { return self.code }
+
+func ExampleMainWith() {
+	opt := try.MainOpt{
+		Out:  os.Stdout,
+		Exit: func(code int) { fmt.Println(`exit code:`, code) },
+	}
+
+	try.MainWith(opt, func(context.Context) {})
+
+	try.MainWith(opt, func(context.Context) {
+		defer try.Detail(`failed to run`)
+		try.To(errors.New(`failure`))
+	})
+
+	try.MainWith(opt, func(context.Context) {
+		defer try.Detail(`failed to build`)
+		panic(exitError{3})
+	})
+
+	try.MainWith(opt, func(context.Context) {
+		defer try.Classify(try.Class{Code: 2, Severity: try.SeverityWarn, UserFacing: true})
+		panic(`nothing to deploy`)
+	})
+
+	try.MainWith(opt, func(ctx context.Context) {
+		proc := try.Interface(os.FindProcess(os.Getpid())).(*os.Process)
+		try.To(proc.Signal(os.Interrupt))
+		<-ctx.Done()
+		try.To(ctx.Err())
+	})
+	// Output:
+	// failed to run: failure
+	// exit code: 1
+	// failed to build: exit 3
+	// exit code: 3
+	// warn: nothing to deploy
+	// exit code: 2
+	// interrupted
+	// exit code: 130
+}
+
+func ExampleMainOpt_log() {
+	opt := try.MainOpt{
+		Exit: func(code int) { fmt.Println(`exit code:`, code) },
+		Log: func(err error) {
+			fmt.Printf("level=%v msg=%q\n", try.SeverityOf(err), err.Error())
+		},
+	}
+
+	try.MainWith(opt, func(context.Context) {
+		defer try.Detail(`failed to run`)
+		try.To(errors.New(`failure`))
+	})
+	// Output:
+	// level=error msg="failed to run: failure"
+	// exit code: 1
+}
+
+func ExampleClassify() {
+	err := try.Catch(func() {
+		defer try.Classify(try.Class{Code: 2, UserFacing: true})
+		defer try.Detail(`invalid config`)
+		try.To(exitError{3})
+	})
+
+	fmt.Println(err)
+	fmt.Println(try.ExitCodeOf(err), try.SeverityOf(err), try.IsUserFacing(err))
+	fmt.Println(len(try.Links(err)))
+	// Output:
+	// invalid config: exit 3
+	// 2 error true
+	// 2
+}
+
+func ExampleClassOf() {
+	err := try.Classified(errors.New(`cache miss`), try.Class{Severity: try.SeverityDebug})
+	err = try.Classified(errors.WithMessage(err, `failed to load`), try.Class{Severity: try.SeverityWarn})
+
+	fmt.Printf("%+v\n", try.ClassOf(err))
+	fmt.Println(try.SeverityOf(err), try.ExitCodeOf(err))
+	fmt.Println(try.SeverityOf(nil), try.ExitCodeOf(nil))
+	// Output:
+	// {Code:0 Severity:warn UserFacing:false}
+	// warn 1
+	//  0
+}
+
+func ExampleDetailPublic() {
+	err := try.Catch(func() {
+		defer try.DetailPublic(`failed to save the order`)
+		defer try.Detail(`failed to save order 123 to shard 7`)
+		panic(`connection refused`)
+	})
+
+	fmt.Println(err)
+	fmt.Println(try.PublicMessage(err))
+	fmt.Println(try.PublicMessage(errors.New(`password of user 123 expired`)))
+	// Output:
+	// failed to save order 123 to shard 7: connection refused
+	// failed to save the order
+	// internal error
+}
+
+func ExamplePublicMessage() {
+	err := try.Catch(func() {
+		defer try.Detailf(`failed to load %v`, `/etc/app/config.toml`)
+		defer try.Classify(try.Class{UserFacing: true})
+		panic(`invalid port number`)
+	})
+
+	fmt.Println(err)
+	fmt.Println(try.PublicMessage(err))
+	// Output:
+	// failed to load /etc/app/config.toml: invalid port number
+	// invalid port number
+}
+
+func ExampleSecret() {
+	err := try.Catch(func() {
+		defer try.Detailf(`failed to log in as %v with token %v`, `alice`, try.Secret(`s3cr3t`))
+		panic(`unauthorized`)
+	})
+
+	fmt.Println(err)
+	fmt.Println(strings.Contains(fmt.Sprintf(`%+v`, err), `s3cr3t`))
+	fmt.Println(try.Reveal(err))
+	// Output:
+	// failed to log in as alice with token [REDACTED]: unauthorized
+	// false
+	// failed to log in as alice with token s3cr3t: unauthorized
+}
+
+func ExampleRedact() {
+	prev := try.Redact
+	defer func() { try.Redact = prev }()
+
+	try.Redact = func(val interface{}) string {
+		str := fmt.Sprint(val)
+		return `***` + str[len(str)-2:]
+	}
+
+	var err error
+	func() {
+		defer try.WithMessagef(&err, `invalid card %v`, try.Secret(`4111111111111111`))
+		err = errors.New(`declined`)
+	}()
+	fmt.Println(err)
+	// Output:
+	// invalid card ***11: declined
+}
+
+func ExampleDetailFunc() {
+	process := func(val int) {
+		defer try.DetailFunc(func() string { return fmt.Sprintf(`failed to process %v`, val) })
+		if val > 1 {
+			panic(`too large`)
+		}
+	}
+
+	fmt.Println(try.Catch(func() { process(1) }))
+	fmt.Println(try.Catch(func() { process(2) }))
+	// Output:
+	// <nil>
+	// failed to process 2: too large
+}
+
+func ExampleRecWithMessageFunc() {
+	someFunc := func(val int) (err error) {
+		defer try.RecWithMessageFunc(&err, func() string { return fmt.Sprintf(`failed to process %v`, val) })
+		panic(`failure`)
+	}
+	fmt.Println(someFunc(1))
+	// Output:
+	// failed to process 1: failure
+}
+
+func ExampleRetry() {
+	var count int
+	try.Retry(context.Background(), try.RetryPolicy{Attempts: 5}, func() {
+		count++
+		if count < 3 {
+			panic(`temporary failure`)
+		}
+	})
+	fmt.Println(`succeeded after`, count, `attempts`)
+
+	err := try.Catch(func() {
+		try.Retry(context.Background(), try.RetryPolicy{Backoff: try.ConstantBackoff(time.Millisecond)}, func() {
+			panic(`permanent failure`)
+		})
+	})
+	fmt.Println(err)
+
+	var retryErr *try.RetryError
+	fmt.Println(errors.As(err, &retryErr), len(retryErr.Errs))
+	// Output:
+	// succeeded after 3 attempts
+	// failed after 3 attempts: permanent failure
+	// true 3
+}
+
+func ExampleRetry_test() {
+	errNotFound := errors.New(`not found`)
+	policy := try.RetryPolicy{
+		Attempts: 5,
+		Test:     func(err error) bool { return !errors.Is(err, errNotFound) },
+	}
+
+	var count int
+	err := try.Catch(func() {
+		try.Retry(context.Background(), policy, func() {
+			count++
+			try.To(errNotFound)
+		})
+	})
+	fmt.Println(err, count)
+	// Output:
+	// not found 1
+}
+
+func ExampleRetry_context() {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := try.RetryPolicy{Attempts: 5, Backoff: try.ConstantBackoff(time.Hour)}
+
+	err := try.Catch(func() {
+		try.Retry(ctx, policy, func() {
+			cancel()
+			panic(`failure`)
+		})
+	})
+	fmt.Println(err)
+	fmt.Println(errors.Is(err, context.Canceled))
+	// Output:
+	// failed after 1 attempt (context canceled): failure
+	// true
+}
+
+func ExampleRetry_maxElapsed() {
+	// Each attempt takes a second on this clock.
+	var now time.Time
+	policy := try.RetryPolicy{
+		MaxElapsed: 2500 * time.Millisecond,
+		Now:        func() time.Time { return now },
+	}
+	fail := func() {
+		now = now.Add(time.Second)
+		panic(`failure`)
+	}
+
+	fmt.Println(try.Catch(func() { try.Retry(context.Background(), policy, fail) }))
+
+	// Retrying stops without waiting when the delay would exceed the limit.
+	policy.Backoff = try.ConstantBackoff(time.Hour)
+	fmt.Println(try.Catch(func() { try.Retry(context.Background(), policy, fail) }))
+	// Output:
+	// failed after 3 attempts: failure
+	// failed after 1 attempt: failure
+}
+
+func ExampleExponentialBackoff() {
+	backoff := try.ExponentialBackoff(100*time.Millisecond, time.Second)
+	for attempt := 1; attempt <= 6; attempt++ {
+		fmt.Println(backoff(attempt))
+	}
+
+	jittered := try.JitteredBackoff(backoff)
+	delay := jittered(3)
+	fmt.Println(delay >= 200*time.Millisecond && delay <= 400*time.Millisecond)
+	// Output:
+	// 100ms
+	// 200ms
+	// 400ms
+	// 800ms
+	// 1s
+	// 1s
+	// true
+}
+
+func ExampleIsRetryable() {
+	fmt.Println(try.IsRetryable(errors.New(`failure`)))
+	fmt.Println(try.IsRetryable(errors.WithStack(context.DeadlineExceeded)))
+	fmt.Println(try.IsRetryable(context.Canceled))
+	fmt.Println(try.IsRetryable(&os.SyscallError{Syscall: `read`, Err: syscall.ECONNRESET}))
+
+	err := try.Catch(func() {
+		defer try.MarkPermanent()
+		try.To(context.DeadlineExceeded)
+	})
+	fmt.Println(err, try.IsRetryable(err))
+	// Output:
+	// false
+	// true
+	// false
+	// true
+	// context deadline exceeded false
+}
+
+// Implements only the `Cause` method of "github.com/pkg/errors".
+type causeOnly struct{ cause error }
+
+func (self causeOnly) Error() string { return `wrapped: ` + self.cause.Error() }
+func (self causeOnly) Cause() error  { return self.cause }
+
+func ExampleIsRetryable_wrapped() {
+	timeout := context.DeadlineExceeded
+	fmt.Println(try.IsRetryable(causeOnly{timeout}))
+	fmt.Println(try.IsRetryable(fmt.Errorf(`%w; %w`, errors.New(`failure`), timeout)))
+	fmt.Println(try.IsRetryable(fmt.Errorf(`%w; %w`, timeout, context.Canceled)))
+	// Output:
+	// true
+	// true
+	// false
+}
+
+func ExampleMarkRetryableOnly() {
+	errBusy := errors.New(`busy`)
+	isBusy := func(err error) bool { return errors.Is(err, errBusy) }
+
+	var count int
+	try.Retry(context.Background(), try.RetryPolicy{Test: try.IsRetryable}, func() {
+		defer try.MarkRetryableOnly(isBusy)
+		count++
+		if count < 3 {
+			try.To(errBusy)
+		}
+	})
+	fmt.Println(count)
+	// Output:
+	// 3
+}
+
+func ExampleBreaker() {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := &try.Breaker{
+		Threshold: 2,
+		Timeout:   time.Minute,
+		Now:       func() time.Time { return now },
+	}
+
+	call := func(fail bool) {
+		err := try.Catch(func() {
+			breaker.Run(func() {
+				if fail {
+					panic(`downstream failure`)
+				}
+			})
+		})
+		fmt.Printf("%v; %v\n", err, breaker.State())
+	}
+
+	call(true)
+	call(true)
+	call(false)
+
+	now = now.Add(time.Minute)
+	fmt.Println(breaker.State())
+	call(true)
+
+	now = now.Add(time.Minute)
+	call(false)
+
+	call(true)
+	call(true)
+	err := try.Catch(func() { breaker.Run(func() {}) })
+
+	var open *try.OpenError
+	fmt.Println(errors.Is(err, try.ErrOpen), errors.As(err, &open), open.Until.Sub(now))
+	// Output:
+	// downstream failure; closed
+	// downstream failure; open
+	// circuit breaker is open; open
+	// half-open
+	// downstream failure; open
+	// <nil>; closed
+	// downstream failure; closed
+	// downstream failure; open
+	// true true 1m0s
+}
+
+// Outcomes of calls which started before the latest change of the state are
+// ignored.
+func ExampleBreaker_stale() {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := &try.Breaker{
+		Threshold: 1,
+		Timeout:   time.Minute,
+		Now:       func() time.Time { return now },
+	}
+
+	// Slow call which started while the circuit was closed.
+	try.Catch(func() {
+		breaker.Run(func() {
+			try.Catch(func() { breaker.Run(func() { panic(`downstream failure`) }) })
+			fmt.Println(breaker.State())
+
+			now = now.Add(time.Minute)
+			breaker.Run(func() {})
+			fmt.Println(breaker.State())
+
+			panic(`late failure`)
+		})
+	})
+	fmt.Println(breaker.State())
+	// Output:
+	// open
+	// closed
+	// closed
+}
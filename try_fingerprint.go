@@ -0,0 +1,154 @@
+package try
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/*
+Options for `FingerprintWith`. `Frames` is the amount of in-app frames to
+consider, defaulting to 3. `Lines` includes line numbers, which makes the
+fingerprint more precise but sensitive to unrelated edits of the same file.
+*/
+type FingerprintOpt struct {
+	Frames int
+	Lines  bool
+}
+
+/*
+Returns a stable identifier of an error, suitable for grouping and
+deduplicating repeated failures. Built from the types of the error chain (see
+`Links`) and the innermost in-app frames of its stacktrace (see
+`Frame.IsInternal`). Error messages are ignored, because they often embed
+variable data such as IDs. Line numbers are ignored by default, to tolerate
+line drift between builds.
+
+Returns "" for nil.
+*/
+func Fingerprint(err error) string {
+	return FingerprintWith(err, FingerprintOpt{})
+}
+
+// Version of `Fingerprint` with custom options.
+func FingerprintWith(err error, opt FingerprintOpt) string {
+	if err == nil {
+		return ``
+	}
+
+	limit := opt.Frames
+	if limit <= 0 {
+		limit = 3
+	}
+
+	hash := sha256.New()
+	write := func(val string) {
+		_, _ = hash.Write([]byte(val))
+		_, _ = hash.Write([]byte{0})
+	}
+
+	for _, link := range Links(err) {
+		write(link.Type)
+	}
+
+	for _, frame := range appFrames(StackOf(err), limit) {
+		write(frame.Func)
+		if opt.Lines {
+			write(strconv.Itoa(frame.Line))
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
+
+/*
+Returns a function suitable for `RecWith` and `Fail`, which calls the provided
+function only for the first error with a given `Fingerprint` within the given
+interval, dropping repeats. Safe for concurrent use.
+*/
+func Dedup(interval time.Duration, fun func(error)) func(error) {
+	return DedupWith(DedupOpt{Interval: interval}, fun)
+}
+
+/*
+Options for `DedupWith`. `Interval` is how long repeats of an error are
+dropped. `Now` is called once per reported error, to timestamp it and to expire
+older entries; it defaults to `time.Now`. A fake clock allows to test the
+expiry of repeats without waiting for `Interval`.
+*/
+type DedupOpt struct {
+	Interval time.Duration
+	Now      func() time.Time
+}
+
+// Version of `Dedup` with custom options.
+func DedupWith(opt DedupOpt, fun func(error)) func(error) {
+	var mut sync.Mutex
+	seen := map[string]time.Time{}
+
+	return func(err error) {
+		if err == nil || fun == nil {
+			return
+		}
+
+		key := Fingerprint(err)
+		now := opt.now()
+
+		mut.Lock()
+		prev, ok := seen[key]
+		if ok && now.Sub(prev) < opt.Interval {
+			mut.Unlock()
+			return
+		}
+		for key, val := range seen {
+			if now.Sub(val) >= opt.Interval {
+				delete(seen, key)
+			}
+		}
+		seen[key] = now
+		mut.Unlock()
+
+		fun(err)
+	}
+}
+
+func (self DedupOpt) now() time.Time {
+	if self.Now != nil {
+		return self.Now()
+	}
+	return time.Now()
+}
+
+/*
+Innermost in-app frames. When none of them are in-app, falls back on the
+innermost frames outside of this module and the runtime, and finally on the
+innermost frames.
+*/
+func appFrames(frames []Frame, limit int) []Frame {
+	out := filterFrames(frames, limit, func(frame Frame) bool { return !frame.IsInternal() })
+	if len(out) == 0 {
+		out = filterFrames(frames, limit, func(frame Frame) bool {
+			pkg := frame.Pkg()
+			return !isOwnPkg(pkg) && pkg != `runtime`
+		})
+	}
+	if len(out) == 0 {
+		out = filterFrames(frames, limit, func(Frame) bool { return true })
+	}
+	return out
+}
+
+func filterFrames(frames []Frame, limit int, test func(Frame) bool) []Frame {
+	var out []Frame
+	for _, frame := range frames {
+		if len(out) >= limit {
+			break
+		}
+		if test(frame) {
+			out = append(out, frame)
+		}
+	}
+	return out
+}
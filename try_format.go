@@ -0,0 +1,224 @@
+package try
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Options for `Format`.
+type FormatOpt struct {
+	// Print the whole error in one line, with frames abbreviated to "file:line".
+	Compact bool
+
+	// Print file paths relative to this directory, when they're located inside
+	// it. See `ModuleRoot`.
+	Root string
+
+	// Use ANSI escape codes for terminal output.
+	Color bool
+
+	// Amount of source lines to print before and after each frame. Zero
+	// disables source snippets. Ignored in compact mode.
+	Source int
+
+	// Omit frames of the runtime, the standard library, and this package. See
+	// `Frame.IsInternal`.
+	SkipInternal bool
+
+	// Maximum amount of frames per stacktrace. Zero means no limit.
+	Frames int
+}
+
+/*
+Formats an error for humans. By default, prints the error chain in Java style,
+outermost first, each cause on a separate "Caused by" line, with stacktraces
+under the layers that captured them:
+
+	*errors.withMessage: failed to X
+	Caused by: string: failure
+		at main.someFunc (main.go:12)
+		at main.main (main.go:20)
+
+See `FormatOpt` for other modes. Returns "" for nil.
+*/
+func Format(err error, opt FormatOpt) string {
+	if err == nil {
+		return ``
+	}
+	fmter := formatter{FormatOpt: opt}
+	if opt.Compact {
+		fmter.compact(err)
+	} else {
+		fmter.full(err)
+	}
+	return fmter.String()
+}
+
+/*
+Returns the root directory of the Go module containing the working directory,
+by searching for "go.mod" in it and its parents. Returns "" if not found.
+Useful for `FormatOpt.Root`.
+*/
+func ModuleRoot() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ``
+	}
+	for {
+		_, err := os.Stat(filepath.Join(dir, `go.mod`))
+		if err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ``
+		}
+		dir = parent
+	}
+}
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+type formatter struct {
+	FormatOpt
+	strings.Builder
+	sources map[string][]string
+}
+
+func (self *formatter) full(err error) {
+	for ind, link := range Links(err) {
+		if ind > 0 {
+			self.WriteString("\n")
+			self.paint(ansiYellow, `Caused by: `)
+		}
+		head := link.Msg
+		if link.Type != `` && head != `` {
+			head = link.Type + `: ` + head
+		} else if link.Type != `` {
+			head = link.Type
+		}
+		self.paint(ansiBold+ansiRed, head)
+
+		for _, frame := range self.frames(link.Stack) {
+			self.WriteString("\n\tat ")
+			self.paint(ansiCyan, frame.Func)
+			self.WriteString(` (`)
+			self.paint(ansiDim, self.location(frame))
+			self.WriteString(`)`)
+			self.snippet(frame)
+		}
+	}
+}
+
+func (self *formatter) compact(err error) {
+	self.paint(ansiBold+ansiRed, err.Error())
+
+	frames := self.frames(StackOf(err))
+	for ind, frame := range frames {
+		if ind == 0 {
+			self.WriteString(` at `)
+		} else {
+			self.WriteString(` < `)
+		}
+		self.paint(ansiDim, self.location(frame))
+	}
+}
+
+func (self *formatter) frames(src []Frame) []Frame {
+	var out []Frame
+	for _, frame := range src {
+		if self.Frames > 0 && len(out) >= self.Frames {
+			break
+		}
+		if self.SkipInternal && frame.IsInternal() {
+			continue
+		}
+		out = append(out, frame)
+	}
+	return out
+}
+
+func (self *formatter) location(frame Frame) string {
+	path := frame.File
+	if self.Compact {
+		path = filepath.Base(path)
+	} else if self.Root != `` {
+		rel, err := filepath.Rel(self.Root, path)
+		if err == nil && !strings.HasPrefix(rel, `..`) {
+			path = rel
+		}
+	}
+	return fmt.Sprintf(`%v:%v`, path, frame.Line)
+}
+
+func (self *formatter) snippet(frame Frame) {
+	if self.Source <= 0 || frame.Line <= 0 {
+		return
+	}
+
+	lines := self.source(frame.File)
+	start := frame.Line - self.Source
+	if start < 1 {
+		start = 1
+	}
+	end := frame.Line + self.Source
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	for num := start; num <= end; num++ {
+		prefix := `  `
+		if num == frame.Line {
+			prefix = `> `
+		}
+		line := fmt.Sprintf("\n\t\t%v%4d | %v", prefix, num, lines[num-1])
+		if num == frame.Line {
+			self.paint(ansiBold, line)
+		} else {
+			self.paint(ansiDim, line)
+		}
+	}
+}
+
+// Missing or unreadable files result in no lines, which disables the snippet.
+func (self *formatter) source(path string) []string {
+	lines, ok := self.sources[path]
+	if ok {
+		return lines
+	}
+
+	file, err := os.Open(path)
+	if err == nil {
+		defer file.Close()
+		scan := bufio.NewScanner(file)
+		for scan.Scan() {
+			lines = append(lines, scan.Text())
+		}
+	}
+
+	if self.sources == nil {
+		self.sources = map[string][]string{}
+	}
+	self.sources[path] = lines
+	return lines
+}
+
+func (self *formatter) paint(color string, val string) {
+	if self.Color && val != `` {
+		self.WriteString(color)
+		self.WriteString(val)
+		self.WriteString(ansiReset)
+		return
+	}
+	self.WriteString(val)
+}
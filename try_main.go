@@ -0,0 +1,174 @@
+package try
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+/*
+Implemented by errors which determine the exit code of the program, such as
+`*exec.ExitError`. Used by `Main`.
+*/
+type ExitCoder interface{ ExitCode() int }
+
+/*
+Error reported by `Main` and `MainContext` when the program is interrupted by
+SIGINT. Matches `context.Canceled` via `errors.Is`, and has the exit code 130,
+like in shells.
+*/
+var ErrInterrupted error = interruptedError{}
+
+type interruptedError struct{}
+
+func (interruptedError) Error() string     { return `interrupted` }
+func (interruptedError) Is(err error) bool { return err == context.Canceled }
+func (interruptedError) ExitCode() int     { return 130 }
+
+// Options for `MainWith`.
+type MainOpt struct {
+	// Print errors with stacktraces, via `Format`. Also enabled by the
+	// environment variable "TRY_VERBOSE" set to a true value such as "1".
+	// Can be set from a command-line flag.
+	Verbose bool
+
+	// Destination of error messages. Defaults to `os.Stderr`.
+	Out io.Writer
+
+	// Called with the exit code on failure. Defaults to `os.Exit`.
+	Exit func(int)
+
+	// Called with the error instead of printing it, for example to send it to
+	// a structured logger. `SeverityOf` and `PublicMessage` are useful for
+	// choosing the level and the message. Doesn't affect the exit code.
+	Log func(error)
+}
+
+/*
+Entry point for programs in the "exceptions" style. Runs the function, catching
+its panics. On success, simply returns. On failure, prints the error and exits
+with a non-zero code. See `MainWith` for details. Usage:
+
+	func main() { try.Main(run) }
+
+On SIGINT, exits immediately with `ErrInterrupted`. Use `MainContext` to shut
+down gracefully.
+*/
+func Main(fun func()) {
+	MainOpt{}.run(false, func(context.Context) { fun() })
+}
+
+/*
+Like `Main`, but passes a context which is canceled on SIGINT, with the cause
+`ErrInterrupted`. A second SIGINT exits immediately. Cancellation errors caused
+by SIGINT are reported as `ErrInterrupted`.
+*/
+func MainContext(fun func(context.Context)) {
+	MainOpt{}.run(true, fun)
+}
+
+/*
+Like `MainContext`, but with custom options.
+
+By default, prints only the error message; in verbose mode, prints the full
+error chain with stacktraces. For errors classified as user-facing via
+`Classify`, prints `PublicMessage` instead, which omits the internal details
+added around it. When the error has a classified severity, the message is
+prefixed with it, for example "warn: ". `MainOpt.Log` replaces printing.
+
+Exit code is determined by `ExitCodeOf`: it can be set via `Classify`, or by
+errors implementing `ExitCoder`, and is 1 otherwise.
+*/
+func MainWith(opt MainOpt, fun func(context.Context)) {
+	opt.run(true, fun)
+}
+
+func (self MainOpt) run(graceful bool, fun func(context.Context)) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// The signal handler and the function may fail at the same time. Only the
+	// first failure is reported. Finishing also claims the slot, so that a late
+	// signal doesn't report anything.
+	var once sync.Once
+	fail := func(err error) { once.Do(func() { self.fail(err) }) }
+	defer once.Do(func() {})
+
+	go func() {
+		for count := 0; ; count++ {
+			select {
+			case <-done:
+				return
+			case <-sig:
+				if graceful && count == 0 {
+					cancel(ErrInterrupted)
+					continue
+				}
+				fail(ErrInterrupted)
+				return
+			}
+		}
+	}()
+
+	err := Catch(func() { fun(ctx) })
+	if err == nil {
+		return
+	}
+	if errors.Is(err, context.Canceled) && errors.Is(context.Cause(ctx), ErrInterrupted) {
+		err = ErrInterrupted
+	}
+	fail(err)
+}
+
+func (self MainOpt) fail(err error) {
+	if self.Log != nil {
+		self.Log(err)
+	} else {
+		self.print(err)
+	}
+
+	exit := self.Exit
+	if exit == nil {
+		exit = os.Exit
+	}
+	exit(ExitCodeOf(err))
+}
+
+func (self MainOpt) print(err error) {
+	out := self.Out
+	if out == nil {
+		out = os.Stderr
+	}
+
+	var msg string
+	if self.Verbose || envVerbose() {
+		msg = Format(err, FormatOpt{Root: ModuleRoot()})
+	} else if IsUserFacing(err) {
+		msg = PublicMessage(err)
+	} else {
+		msg = err.Error()
+	}
+
+	if sev := ClassOf(err).Severity; sev != 0 {
+		msg = sev.String() + `: ` + msg
+	}
+	fmt.Fprintln(out, msg)
+}
+
+func envVerbose() bool {
+	val, _ := strconv.ParseBool(os.Getenv(`TRY_VERBOSE`))
+	return val
+}
@@ -0,0 +1,57 @@
+package try_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mitranim/try"
+	"github.com/pkg/errors"
+)
+
+// The signal handler and the function fail at about the same time, and only
+// one of the failures must be reported.
+func TestMainWith_failOnce(t *testing.T) {
+	var lock sync.Mutex
+	var out bytes.Buffer
+	var codes []int
+
+	opt := try.MainOpt{
+		Out: writerFunc(func(src []byte) (int, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			return out.Write(src)
+		}),
+		Exit: func(code int) {
+			lock.Lock()
+			defer lock.Unlock()
+			codes = append(codes, code)
+		},
+	}
+
+	try.MainWith(opt, func(ctx context.Context) {
+		proc := try.Interface(os.FindProcess(os.Getpid())).(*os.Process)
+		try.To(proc.Signal(os.Interrupt))
+		<-ctx.Done()
+		try.To(proc.Signal(os.Interrupt))
+		time.Sleep(10 * time.Millisecond)
+		try.To(errors.New(`failure`))
+	})
+
+	// A late failure of the signal handler must not be reported either.
+	time.Sleep(10 * time.Millisecond)
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(codes) != 1 || strings.Count(out.String(), "\n") != 1 {
+		t.Fatalf("expected one failure, got exit codes %v and output %q", codes, out.String())
+	}
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (self writerFunc) Write(src []byte) (int, error) { return self(src) }
@@ -0,0 +1,194 @@
+package try
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+Result of `ParsePanic`. `Err` is the parsed error, with the stacktrace of the
+failing goroutine, if known. `Goroutines` lists all goroutines found in a Go
+runtime dump, in their original order, and is empty for other inputs.
+*/
+type Dump struct {
+	Err        *RemoteError `json:"err,omitempty"`
+	Goroutines []Goroutine  `json:"goroutines,omitempty"`
+}
+
+// Single goroutine parsed from a Go runtime dump. See `ParsePanic`.
+type Goroutine struct {
+	ID        int     `json:"id"`
+	State     string  `json:"state,omitempty"`
+	Stack     []Frame `json:"stack,omitempty"`
+	CreatedBy *Frame  `json:"createdBy,omitempty"`
+}
+
+/*
+Parses the crash output of a Go process into structured data. Supports Go
+runtime panics and goroutine dumps, which start with "panic:" or "fatal error:"
+followed by "goroutine N [state]:" sections, and errors from
+"github.com/pkg/errors" printed via `%+v`, where each message is followed by the
+stacktrace captured with it. Lines that don't fit the detected format are
+ignored. Panics if reading fails.
+*/
+func ParsePanic(src io.Reader) Dump {
+	var lines []string
+	scan := bufio.NewScanner(src)
+	scan.Buffer(nil, 1024*1024)
+	for scan.Scan() {
+		lines = append(lines, strings.TrimRight(scan.Text(), "\r"))
+	}
+	To(scan.Err())
+
+	for _, line := range lines {
+		if reGoroutine.MatchString(line) {
+			return parseRuntime(lines)
+		}
+	}
+	return Dump{Err: parseTrace(lines)}
+}
+
+var (
+	reGoroutine = regexp.MustCompile(`^goroutine (\d+) \[([^\]]*)\]:$`)
+	reFileLine  = regexp.MustCompile(`^\t(.+):(\d+)(?: \+0x[0-9a-f]+)?$`)
+	reRecovered = regexp.MustCompile(` \[recovered[^\]]*\]$`)
+)
+
+func parseRuntime(lines []string) Dump {
+	var out Dump
+	var cur *Goroutine
+
+	for ind := 0; ind < len(lines); ind++ {
+		line := lines[ind]
+
+		if msg, ok := panicMessage(line); ok {
+			// Repeated panics are printed in the order they occurred. The last one
+			// crashed the process, and is treated as wrapping the earlier ones.
+			out.Err = &RemoteError{Type: `panic`, Msg: msg, Cause: out.Err}
+			continue
+		}
+
+		match := reGoroutine.FindStringSubmatch(line)
+		if match != nil {
+			out.Goroutines = append(out.Goroutines, Goroutine{
+				ID:    atoi(match[1]),
+				State: match[2],
+			})
+			cur = &out.Goroutines[len(out.Goroutines)-1]
+			continue
+		}
+
+		if cur == nil || ind+1 >= len(lines) {
+			continue
+		}
+
+		frame, ok := parseFrame(line, lines[ind+1])
+		if !ok {
+			continue
+		}
+		ind++
+
+		if strings.HasPrefix(line, `created by `) {
+			cur.CreatedBy = &frame
+		} else {
+			cur.Stack = append(cur.Stack, frame)
+		}
+	}
+
+	if out.Err != nil && len(out.Goroutines) > 0 {
+		out.Err.Stack = out.Goroutines[0].Stack
+	}
+	return out
+}
+
+func panicMessage(line string) (string, bool) {
+	line = strings.TrimLeft(line, "\t")
+	for _, prefix := range []string{`panic: `, `fatal error: `} {
+		if strings.HasPrefix(line, prefix) {
+			msg := strings.TrimPrefix(line, prefix)
+			// Newer Go versions print "[recovered, repanicked]".
+			msg = reRecovered.ReplaceAllLiteralString(msg, ``)
+			return msg, true
+		}
+	}
+	return ``, false
+}
+
+/*
+Output of `%+v` for "github.com/pkg/errors" lists the innermost error first,
+each message followed by its stacktrace, if any, and each wrapper message on
+its own line.
+*/
+func parseTrace(lines []string) *RemoteError {
+	var out *RemoteError
+	framed := false
+
+	for ind := 0; ind < len(lines); ind++ {
+		line := lines[ind]
+		if strings.TrimSpace(line) == `` {
+			continue
+		}
+
+		if ind+1 < len(lines) {
+			frame, ok := parseFrame(line, lines[ind+1])
+			if ok {
+				ind++
+				// Only the innermost stacktrace is kept; the outer ones, added by
+				// redundant `errors.WithStack`, are its suffixes.
+				if out != nil && !framed {
+					out.Stack = append(out.Stack, frame)
+				}
+				continue
+			}
+		}
+
+		if out != nil && len(out.Stack) > 0 {
+			framed = true
+		}
+		if out == nil {
+			out = &RemoteError{Msg: line}
+		} else {
+			out = &RemoteError{Msg: line + `: ` + out.Msg, Cause: out}
+		}
+	}
+	return out
+}
+
+// Parses a pair of lines such as:
+//
+//	main.someFunc(0x1, ...)
+//		/path/to/main.go:12 +0x25
+func parseFrame(head, tail string) (Frame, bool) {
+	match := reFileLine.FindStringSubmatch(tail)
+	if match == nil || head == `` || strings.HasPrefix(head, "\t") {
+		return Frame{}, false
+	}
+
+	fun := strings.TrimPrefix(head, `created by `)
+	if ind := strings.Index(fun, ` in goroutine `); ind >= 0 {
+		fun = fun[:ind]
+	}
+	if strings.HasSuffix(fun, `)`) {
+		if ind := strings.LastIndexByte(fun, '('); ind > 0 {
+			fun = fun[:ind]
+		}
+	}
+	if strings.ContainsAny(fun, " \t") {
+		return Frame{}, false
+	}
+
+	// The runtime prints `runtime.gopanic` as "panic".
+	if fun == `panic` {
+		fun = `runtime.gopanic`
+	}
+
+	return Frame{Func: fun, File: match[1], Line: atoi(match[2])}, true
+}
+
+func atoi(src string) int {
+	out, _ := strconv.Atoi(src)
+	return out
+}
@@ -0,0 +1,100 @@
+package try
+
+import "github.com/pkg/errors"
+
+/*
+Fallback returned by `PublicMessage` for errors without a public message. Can
+be changed at program start, for example for localization.
+*/
+var DefaultPublicMessage = `internal error`
+
+/*
+Must be deferred. Attaches a public message to non-nil panics, idempotently
+adding a stacktrace. The public message is meant for end users, and is separate
+from the regular message returned by `.Error()`, which remains unchanged and
+may contain internal details. Usage:
+
+	defer try.DetailPublic(`failed to save the order`)
+	defer try.Detailf(`failed to save order %v`, id)
+
+See `PublicMessage`.
+*/
+func DetailPublic(msg string) {
+	To(Public(Err(recover()), msg))
+}
+
+/*
+Wraps the error, attaching a public message without changing the regular
+message. Returns nil for nil. Like `Classified`, the wrapper is elided by
+`Links` and preserves the formatting of the wrapped error.
+*/
+func Public(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return &publicError{transparentOf(err), msg}
+}
+
+/*
+Returns the message of the error which is safe to show to end users: the public
+message of the outermost layer of the error chain which has one, attached via
+`DetailPublic` or `Public`. Otherwise, if the error is classified as
+user-facing (see `Class`), returns the regular message of the outermost layer
+classified as such, without the messages of outer layers. Otherwise returns
+`DefaultPublicMessage`. Returns "" for nil.
+*/
+func PublicMessage(err error) string {
+	msg, ok := publicMessage(err)
+	if ok {
+		return msg
+	}
+	if err == nil {
+		return ``
+	}
+	return DefaultPublicMessage
+}
+
+/*
+True if `PublicMessage` would return a message specific to the error, rather
+than `DefaultPublicMessage`.
+*/
+func HasPublicMessage(err error) bool {
+	_, ok := publicMessage(err)
+	return ok
+}
+
+func publicMessage(err error) (string, bool) {
+	for cause := err; cause != nil; {
+		val, _ := cause.(*publicError)
+		if val != nil {
+			return val.msg, true
+		}
+
+		next := errors.Unwrap(cause)
+		if next == cause {
+			break
+		}
+		cause = next
+	}
+
+	// Only the message of the classified layer is meant for users. Outer layers
+	// may add internal details.
+	for cause := err; cause != nil; {
+		val, _ := cause.(*classError)
+		if val != nil && val.class.UserFacing {
+			return val.Error(), true
+		}
+
+		next := errors.Unwrap(cause)
+		if next == cause {
+			break
+		}
+		cause = next
+	}
+	return ``, false
+}
+
+type publicError struct {
+	transparent
+	msg string
+}
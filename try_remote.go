@@ -0,0 +1,186 @@
+package try
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+/*
+Encodes an error chain as JSON, preserving what's lost when an error crosses a
+process boundary: the message and Go type of each significant layer (see
+`Links`), the layer's exported fields, and its stacktrace. Use `UnmarshalError`
+to decode. Returns "null" for nil.
+*/
+func MarshalError(err error) []byte {
+	return ByteSlice(json.Marshal(remoteFrom(err)))
+}
+
+/*
+Decodes an error previously encoded by `MarshalError` into a chain of
+`*RemoteError`. Panics if the input is malformed. Returns nil if the input
+encodes nil.
+*/
+func UnmarshalError(src []byte) error {
+	var out *RemoteError
+	To(json.Unmarshal(src, &out))
+	if out == nil {
+		return nil
+	}
+	out.link()
+	return out
+}
+
+/*
+Error reconstructed from the output of `MarshalError`. Each layer of the
+original chain becomes a separate `*RemoteError`, linked via `Cause`.
+
+Satisfies `HasStack` when the original error had a stacktrace, which is printed
+by the `%+v` format verb, similarly to errors from "github.com/pkg/errors".
+Panicking such an error via `To` keeps the remote stacktrace without adding a
+local one.
+Supports `errors.Is` against errors registered via `RegisterSentinel`.
+*/
+type RemoteError struct {
+	Type   string          `json:"type,omitempty"`
+	Msg    string          `json:"msg"`
+	Fields json.RawMessage `json:"fields,omitempty"`
+	Stack  []Frame         `json:"stack,omitempty"`
+	Cause  *RemoteError    `json:"cause,omitempty"`
+
+	sentinel error
+}
+
+// Implement `error`. Returns the full message of this layer, including the
+// messages of its causes, exactly as the original error did.
+func (self *RemoteError) Error() string {
+	if self == nil {
+		return ``
+	}
+	return self.Msg
+}
+
+// Implement error unwrapping.
+func (self *RemoteError) Unwrap() error {
+	if self == nil || self.Cause == nil {
+		return nil
+	}
+	return self.Cause
+}
+
+/*
+Implement support for `errors.Is`. True if this layer was decoded from an error
+registered via `RegisterSentinel`, and the target is that error.
+*/
+func (self *RemoteError) Is(target error) bool {
+	return self != nil && self.sentinel != nil && target != nil &&
+		reflect.TypeOf(target).Comparable() && self.sentinel == target
+}
+
+// Returns the decoded stacktrace of this layer. Used by `HasStack`, `StackOf`
+// and `Links`.
+func (self *RemoteError) Frames() []Frame {
+	if self == nil {
+		return nil
+	}
+	return self.Stack
+}
+
+/*
+Implement `fmt.Formatter`. Supports the same verbs as errors from
+"github.com/pkg/errors": `%v` and `%s` print the message, `%q` prints the
+quoted message, and `%+v` additionally prints the remote stacktrace.
+*/
+func (self *RemoteError) Format(out fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if out.Flag('+') {
+			_, _ = io.WriteString(out, self.Error())
+			for _, frame := range StackOf(self) {
+				_, _ = fmt.Fprintf(out, "\n%v", frame)
+			}
+			return
+		}
+		_, _ = io.WriteString(out, self.Error())
+	case 's':
+		_, _ = io.WriteString(out, self.Error())
+	case 'q':
+		_, _ = fmt.Fprintf(out, `%q`, self.Error())
+	}
+}
+
+/*
+Registers an error value, such as `io.EOF` or `sql.ErrNoRows`, so that errors
+decoded by `UnmarshalError` can be compared to it via `errors.Is`. Sentinels
+are matched by Go type and message, and must be registered in the decoding
+process. Safe for concurrent use.
+*/
+func RegisterSentinel(err error) {
+	if err == nil {
+		return
+	}
+	sentinelMut.Lock()
+	defer sentinelMut.Unlock()
+	sentinels[sentinelKey(typeName(err), err.Error())] = err
+}
+
+var (
+	sentinelMut sync.RWMutex
+	sentinels   = map[string]error{}
+)
+
+func sentinelKey(typ, msg string) string { return typ + "\x00" + msg }
+
+func sentinelFor(typ, msg string) error {
+	sentinelMut.RLock()
+	defer sentinelMut.RUnlock()
+	return sentinels[sentinelKey(typ, msg)]
+}
+
+func remoteFrom(err error) *RemoteError {
+	var out *RemoteError
+	var prev *RemoteError
+
+	for _, link := range Links(err) {
+		next := &RemoteError{
+			Type:   link.Type,
+			Msg:    link.Err.Error(),
+			Fields: remoteFields(link.Err),
+			Stack:  link.Stack,
+		}
+
+		// Layers decoded from a previous hop keep their original fields.
+		if val, ok := link.Err.(*RemoteError); ok {
+			next.Fields = val.Fields
+		}
+
+		if prev == nil {
+			out = next
+		} else {
+			prev.Cause = next
+		}
+		prev = next
+	}
+	return out
+}
+
+// Only errors with exported fields produce anything useful; others, including
+// the types in "github.com/pkg/errors", are omitted.
+func remoteFields(err error) json.RawMessage {
+	if _, ok := err.(*RemoteError); ok {
+		return nil
+	}
+	out, fail := json.Marshal(err)
+	if fail != nil || string(out) == `{}` || string(out) == `null` {
+		return nil
+	}
+	return out
+}
+
+func (self *RemoteError) link() {
+	for val := self; val != nil; val = val.Cause {
+		val.sentinel = sentinelFor(val.Type, val.Msg)
+	}
+}
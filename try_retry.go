@@ -0,0 +1,196 @@
+package try
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+/*
+Returns the delay before the next attempt, after the given attempt has failed.
+Attempts are counted from 1. See `ConstantBackoff`, `ExponentialBackoff`,
+`JitteredBackoff`.
+*/
+type Backoff func(attempt int) time.Duration
+
+// Backoff which always waits for the given delay.
+func ConstantBackoff(delay time.Duration) Backoff {
+	return func(int) time.Duration { return delay }
+}
+
+/*
+Backoff which doubles the delay after each attempt, starting with the given
+base delay, up to the given maximum. Zero maximum means no limit.
+*/
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		delay := base
+		for ind := 1; ind < attempt; ind++ {
+			if max > 0 && delay >= max || delay > time.Duration(1<<62)/2 {
+				break
+			}
+			delay *= 2
+		}
+		if max > 0 && delay > max {
+			return max
+		}
+		return delay
+	}
+}
+
+/*
+Randomizes the delays of the given backoff, choosing each delay uniformly
+between half and the whole of the original delay. Prevents clients that
+failed at the same time from retrying at the same time.
+*/
+func JitteredBackoff(backoff Backoff) Backoff {
+	return func(attempt int) time.Duration {
+		delay := backoff(attempt)
+		if delay <= 1 {
+			return delay
+		}
+		half := delay / 2
+		return half + time.Duration(rand.Int63n(int64(delay-half+1)))
+	}
+}
+
+/*
+Options for `Retry`.
+
+`Attempts` is the maximum amount of attempts, including the first one.
+`MaxElapsed` is the maximum total duration; retrying stops when the next delay
+would exceed it. Zero means no limit. If both are zero, `Attempts` defaults to
+3.
+
+`Backoff` decides the delays between attempts. Nil means no delay.
+
+`Test` decides which errors are retryable, like the test in `CatchOnly`. Nil
+means all errors are retryable. `IsRetryable` is a good default, which follows
+the markers of `MarkRetryable` and `MarkPermanent`.
+
+`Now` is the clock which measures the elapsed time for `MaxElapsed`, defaulting
+to `time.Now`. It affects nothing else: delays between attempts still wait on
+real timers, so a fake clock only makes sense with short or zero delays.
+*/
+type RetryPolicy struct {
+	Attempts   int
+	MaxElapsed time.Duration
+	Backoff    Backoff
+	Test       func(error) bool
+	Now        func() time.Time
+}
+
+func (self RetryPolicy) now() time.Time {
+	if self.Now != nil {
+		return self.Now()
+	}
+	return time.Now()
+}
+
+/*
+Runs the function, re-running it when it panics with a retryable error, until
+it succeeds or retries are exhausted. Waits between attempts according to the
+backoff, and stops waiting when the context is done. Panics are converted to
+errors via `Catch`.
+
+When retries are exhausted or stopped by the context, panics with a
+`*RetryError` which records the errors of all attempts. When the first attempt
+fails with an error that isn't retryable, re-panics with that error; after
+several attempts, the error is wrapped in `*RetryError` anyway.
+*/
+func Retry(ctx context.Context, policy RetryPolicy, fun func()) {
+	attempts := policy.Attempts
+	if attempts <= 0 && policy.MaxElapsed <= 0 {
+		attempts = 3
+	}
+
+	start := policy.now()
+	var errs []error
+
+	for attempt := 1; ; attempt++ {
+		err := Catch(fun)
+		if err == nil {
+			return
+		}
+		errs = append(errs, err)
+
+		if policy.Test != nil && !policy.Test(err) {
+			if attempt == 1 {
+				To(err)
+			}
+			To(&RetryError{Errs: errs})
+		}
+		if attempts > 0 && attempt >= attempts {
+			To(&RetryError{Errs: errs})
+		}
+
+		var delay time.Duration
+		if policy.Backoff != nil {
+			delay = policy.Backoff(attempt)
+		}
+		if policy.MaxElapsed > 0 && policy.now().Sub(start)+delay > policy.MaxElapsed {
+			To(&RetryError{Errs: errs})
+		}
+
+		err = wait(ctx, delay)
+		if err != nil {
+			To(&RetryError{Errs: errs, Ctx: err})
+		}
+	}
+}
+
+func wait(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+/*
+Error of `Retry` after several failed attempts. `Errs` are the errors of all
+attempts, in order. `Ctx` is the error of the context, if it stopped retrying.
+
+Unwraps to the error of the last attempt. Also matches the error of the context
+via `errors.Is`.
+*/
+type RetryError struct {
+	Errs []error
+	Ctx  error
+}
+
+// Implement `error`. Includes the message of the last error.
+func (self *RetryError) Error() string {
+	head := `failed after 1 attempt`
+	if len(self.Errs) != 1 {
+		head = fmt.Sprintf(`failed after %v attempts`, len(self.Errs))
+	}
+	if self.Ctx != nil {
+		head += ` (` + self.Ctx.Error() + `)`
+	}
+	return head + `: ` + fmt.Sprint(self.Unwrap())
+}
+
+// Implement error unwrapping. Returns the error of the last attempt.
+func (self *RetryError) Unwrap() error {
+	if len(self.Errs) == 0 {
+		return nil
+	}
+	return self.Errs[len(self.Errs)-1]
+}
+
+// Implement `errors.Is`, matching the error of the context.
+func (self *RetryError) Is(target error) bool {
+	return self.Ctx != nil && errors.Is(self.Ctx, target)
+}
@@ -0,0 +1,146 @@
+package try
+
+import (
+	"context"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+/*
+Must be deferred. Marks non-nil panics as retryable, idempotently adding a
+stacktrace. Doesn't change the error message. See `IsRetryable`.
+*/
+func MarkRetryable() { To(Retryable(Err(recover()), true)) }
+
+/*
+Must be deferred. Marks non-nil panics as permanent, in other words not
+retryable, idempotently adding a stacktrace. See `IsRetryable`.
+*/
+func MarkPermanent() { To(Retryable(Err(recover()), false)) }
+
+/*
+Must be deferred. Marks non-nil panics as retryable, ONLY if they satisfy the
+provided test. Idempotently adds a stacktrace to all panics.
+*/
+func MarkRetryableOnly(test func(error) bool) {
+	err := Err(recover())
+	if err != nil && test != nil && test(err) {
+		err = Retryable(err, true)
+	}
+	To(err)
+}
+
+/*
+Must be deferred. Marks non-nil panics as permanent, ONLY if they satisfy the
+provided test. Idempotently adds a stacktrace to all panics.
+*/
+func MarkPermanentOnly(test func(error) bool) {
+	err := Err(recover())
+	if err != nil && test != nil && test(err) {
+		err = Retryable(err, false)
+	}
+	To(err)
+}
+
+/*
+Wraps the error, marking it as retryable or permanent without changing the
+message. Returns nil for nil. Like `Classified`, the wrapper is elided by
+`Links` and preserves the formatting of the wrapped error.
+*/
+func Retryable(err error, retryable bool) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{transparentOf(err), retryable}
+}
+
+/*
+True if the error is worth retrying. Walks the error chain, outermost first,
+and stops at the first layer which is:
+
+  - Marked via `MarkRetryable`, `MarkPermanent` or `Retryable`.
+  - Implementing `Retryable() bool`, such as `tryhttp.StatusError`.
+  - `context.Canceled`, which is permanent.
+  - Implementing `Timeout() bool` which returns true, such as `net.Error`
+    timeouts, `context.DeadlineExceeded` and `os.ErrDeadlineExceeded`.
+  - A `syscall.Errno` such as `ECONNRESET` or `ECONNREFUSED`, which indicates
+    a transient network failure.
+
+Follows both `Unwrap() error` and the `Cause() error` method of
+"github.com/pkg/errors". Errors wrapping several errors via `Unwrap() []error`,
+such as those of `errors.Join`, are retryable when at least one of the wrapped
+errors is retryable, and none is permanent.
+
+Returns false if no layer matches, and for nil. Suitable for `RetryPolicy`.
+*/
+func IsRetryable(err error) bool {
+	val, _ := retryable(err)
+	return val
+}
+
+// Second result is false when no layer has decided.
+func retryable(err error) (bool, bool) {
+	for err != nil {
+		switch val := err.(type) {
+		case *retryableError:
+			return val.retryable, true
+		case interface{ Retryable() bool }:
+			return val.Retryable(), true
+		case syscall.Errno:
+			return isRetryableErrno(val), true
+		}
+
+		if err == context.Canceled {
+			return false, true
+		}
+		if val, ok := err.(interface{ Timeout() bool }); ok && val.Timeout() {
+			return true, true
+		}
+
+		if val, ok := err.(interface{ Unwrap() []error }); ok {
+			return retryableAll(val.Unwrap())
+		}
+
+		cause := errors.Unwrap(err)
+		if cause == nil {
+			if val, ok := err.(interface{ Cause() error }); ok {
+				cause = val.Cause()
+			}
+		}
+		if cause == err {
+			break
+		}
+		err = cause
+	}
+	return false, false
+}
+
+func retryableAll(errs []error) (out bool, decided bool) {
+	for _, err := range errs {
+		val, ok := retryable(err)
+		if !ok {
+			continue
+		}
+		if !val {
+			return false, true
+		}
+		out, decided = true, true
+	}
+	return
+}
+
+func isRetryableErrno(val syscall.Errno) bool {
+	switch val {
+	case syscall.ECONNRESET, syscall.ECONNREFUSED, syscall.ECONNABORTED,
+		syscall.ETIMEDOUT, syscall.EPIPE, syscall.EAGAIN:
+		return true
+	default:
+		return val.Timeout() || val.Temporary()
+	}
+}
+
+type retryableError struct {
+	transparent
+	retryable bool
+}
@@ -0,0 +1,132 @@
+package try
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+/*
+Redaction policy used for formatting `Sensitive` values. Returns "[REDACTED]"
+by default. Can be changed at program start, for example to show the last few
+characters of tokens, or a hash which identifies a value without revealing it.
+*/
+var Redact = func(interface{}) string { return `[REDACTED]` }
+
+/*
+Marks a value as sensitive. Usage:
+
+	defer try.Detailf(`failed to log in as %v with token %v`, email, try.Secret(token))
+
+The resulting value is formatted via `Redact` by "fmt", including `%+v` and
+`%#v`, and encoding packages using `encoding.TextMarshaler`. The messages
+formatted by `Detailf`, `DetailOnlyf`, `RecWithMessagef` and `WithMessagef`
+are therefore redacted, both in `.Error()` and in `%+v`. These functions also
+retain the original arguments, which can be revealed via `Reveal`.
+*/
+func Secret(val interface{}) Sensitive { return Sensitive{val} }
+
+/*
+Sensitive value created by `Secret`. The original value is available as `.Val`
+and is never formatted implicitly.
+*/
+type Sensitive struct{ Val interface{} }
+
+// Implement `fmt.Stringer`. Redacted via `Redact`.
+func (self Sensitive) String() string { return Redact(self.Val) }
+
+// Implement `fmt.GoStringer`. Redacted via `Redact`.
+func (self Sensitive) GoString() string { return Redact(self.Val) }
+
+// Implement `fmt.Formatter`. Redacted via `Redact` for every verb.
+func (self Sensitive) Format(out fmt.State, _ rune) {
+	_, _ = io.WriteString(out, Redact(self.Val))
+}
+
+// Implement `encoding.TextMarshaler`. Redacted via `Redact`.
+func (self Sensitive) MarshalText() ([]byte, error) {
+	return []byte(Redact(self.Val)), nil
+}
+
+/*
+Returns the message of the error with the sensitive arguments of the "try"
+functions revealed, see `Secret`. Meant for privileged code, such as
+debugging tools with restricted access. Messages formatted elsewhere, for
+example via `fmt.Errorf`, remain redacted. Returns "" for nil.
+*/
+func Reveal(err error) string {
+	if err == nil {
+		return ``
+	}
+
+	if val, ok := err.(*secretMessage); ok {
+		return val.reveal() + `: ` + Reveal(val.cause)
+	}
+
+	cause := errors.Unwrap(err)
+	if cause == nil || cause == err {
+		return err.Error()
+	}
+
+	msg, own := err.Error(), cause.Error()
+	if !strings.HasSuffix(msg, own) {
+		return msg
+	}
+	return strings.TrimSuffix(msg, own) + Reveal(cause)
+}
+
+/*
+Equivalent of `errors.WithMessagef` which retains sensitive arguments for
+`Reveal`. Without sensitive arguments, simply calls `errors.WithMessagef`.
+*/
+func withMessagef(err error, pattern string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	for _, arg := range args {
+		if _, ok := arg.(Sensitive); ok {
+			return &secretMessage{causer{err}, pattern, args}
+		}
+	}
+	return errors.WithMessagef(err, pattern, args...)
+}
+
+// Formats like `errors.WithMessagef`, but lazily, redacting sensitive args.
+type secretMessage struct {
+	causer
+	pattern string
+	args    []interface{}
+}
+
+func (self *secretMessage) Error() string {
+	return fmt.Sprintf(self.pattern, self.args...) + `: ` + self.cause.Error()
+}
+
+func (self *secretMessage) Format(out fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if out.Flag('+') {
+			fmt.Fprintf(out, "%+v\n", self.cause)
+			fmt.Fprintf(out, self.pattern, self.args...)
+			return
+		}
+		_, _ = io.WriteString(out, self.Error())
+	case 's':
+		_, _ = io.WriteString(out, self.Error())
+	case 'q':
+		fmt.Fprintf(out, `%q`, self.Error())
+	}
+}
+
+func (self *secretMessage) reveal() string {
+	args := make([]interface{}, len(self.args))
+	for ind, arg := range self.args {
+		if val, ok := arg.(Sensitive); ok {
+			arg = val.Val
+		}
+		args[ind] = arg
+	}
+	return fmt.Sprintf(self.pattern, args...)
+}
@@ -0,0 +1,274 @@
+package try
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Hidden interface implemented by some types in "github.com/pkg/errors".
+type stackTracer interface{ StackTrace() errors.StackTrace }
+
+// Implemented by errors that carry already-decoded frames, such as
+// `RemoteError`.
+type framer interface{ Frames() []Frame }
+
+/*
+Single decoded stack frame. Unlike `errors.Frame` from "github.com/pkg/errors",
+this is plain data, which makes it suitable for encoding, comparison, and
+printing in custom formats.
+*/
+type Frame struct {
+	Func string `json:"func,omitempty"`
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+// Decodes a frame from a "github.com/pkg/errors" stacktrace.
+func FrameFrom(val errors.Frame) Frame {
+	pc := uintptr(val) - 1
+	fun := runtime.FuncForPC(pc)
+	if fun == nil {
+		return Frame{Func: `unknown`, File: `unknown`}
+	}
+	file, line := fun.FileLine(pc)
+	return Frame{Func: fun.Name(), File: file, Line: line}
+}
+
+/*
+Package path of the frame's function. For example, for
+"github.com/mitranim/try.(*Val).Error" this returns "github.com/mitranim/try".
+*/
+func (self Frame) Pkg() string {
+	pkg, _ := splitFunc(self.Func)
+	return pkg
+}
+
+/*
+Name of the frame's function without its package path. For example, for
+"github.com/mitranim/try.(*Val).Error" this returns "(*Val).Error".
+*/
+func (self Frame) Name() string {
+	_, name := splitFunc(self.Func)
+	return name
+}
+
+/*
+True if the frame belongs to the Go runtime, the standard library, or this
+module, including subpackages such as "tryhttp". Such frames are plumbing
+rather than application code, and are usually skipped when grouping or
+displaying errors. Test packages, such as those of examples, are not internal.
+
+Packages of the main module and its dependencies, as reported by
+`debug.ReadBuildInfo`, are never considered standard, even when their module
+path has no domain, such as "myapp".
+*/
+func (self Frame) IsInternal() bool {
+	pkg := self.Pkg()
+	if pkg == `` || pkg == `main` {
+		return false
+	}
+	if isOwnPkg(pkg) {
+		return true
+	}
+	if isModulePkg(pkg) {
+		return false
+	}
+
+	// Standard library import paths don't have a domain in the first segment.
+	head := pkg
+	if ind := strings.IndexByte(head, '/'); ind >= 0 {
+		head = head[:ind]
+	}
+	return !strings.Contains(head, `.`)
+}
+
+// True if the package is this package or one of its subpackages.
+func isOwnPkg(pkg string) bool {
+	if strings.HasSuffix(pkg, `_test`) {
+		return false
+	}
+	return pkg == pkgPath || strings.HasPrefix(pkg, pkgPath+`/`)
+}
+
+var modulePathsOnce sync.Once
+var modulePaths []string
+
+// True if the package belongs to the main module or one of its dependencies.
+func isModulePkg(pkg string) bool {
+	modulePathsOnce.Do(func() {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		if info.Main.Path != `` {
+			modulePaths = append(modulePaths, info.Main.Path)
+		}
+		for _, dep := range info.Deps {
+			modulePaths = append(modulePaths, dep.Path)
+		}
+	})
+
+	for _, path := range modulePaths {
+		if pkg == path || strings.HasPrefix(pkg, path+`/`) {
+			return true
+		}
+	}
+	return false
+}
+
+// Implement `fmt.Stringer`, using the same layout as Go panic traces.
+func (self Frame) String() string {
+	return fmt.Sprintf("%v\n\t%v:%v", self.Func, self.File, self.Line)
+}
+
+/*
+Returns the deepest stacktrace found in the error chain, decoded into frames,
+innermost first. The deepest trace is the closest to the origin of the error.
+Returns nil if there's no stacktrace.
+*/
+func StackOf(err error) []Frame {
+	var trace []Frame
+	for err != nil {
+		own := ownStack(err)
+		if own != nil {
+			trace = own
+		}
+		cause := errors.Unwrap(err)
+		if cause == err {
+			break
+		}
+		err = cause
+	}
+	return trace
+}
+
+/*
+Single significant layer of an error chain. See `Links`.
+
+`Msg` is the layer's own message, without the messages of the errors it wraps.
+`Type` is the Go type of the layer; for `Val`, this is the type of the panic
+value; for `RemoteError`, this is the original type, which may be unknown.
+`Stack` is the stacktrace captured at this layer, if any.
+*/
+type Link struct {
+	Err   error
+	Type  string
+	Msg   string
+	Stack []Frame
+}
+
+/*
+Breaks down an error chain into significant layers, outermost first. Wrappers
+that only add a stacktrace, such as those created by `errors.WithStack`, are
+elided, and their stacktrace is attributed to the error they wrap, unless the
+wrapped chain already has a stacktrace, which is then preferred. Wrappers of
+this package that don't change the message, such as those created by
+`Classified`, are elided too.
+*/
+func Links(err error) []Link {
+	var out []Link
+	var trace []Frame
+
+	for err != nil {
+		cause := errors.Unwrap(err)
+		if cause == err {
+			cause = nil
+		}
+
+		own := ownStack(err)
+		if cause != nil && own != nil && err.Error() == cause.Error() {
+			if !HasStack(cause) {
+				trace = own
+			}
+			err = cause
+			continue
+		}
+
+		if _, ok := err.(transparentError); ok && cause != nil {
+			err = cause
+			continue
+		}
+
+		if _, ok := err.(Val); ok && cause != nil {
+			err = cause
+			continue
+		}
+
+		if own != nil {
+			trace = own
+		}
+		out = append(out, Link{
+			Err:   err,
+			Type:  typeName(err),
+			Msg:   ownMessage(err, cause),
+			Stack: trace,
+		})
+		trace = nil
+		err = cause
+	}
+	return out
+}
+
+const pkgPath = `github.com/mitranim/try`
+
+func ownStack(err error) []Frame {
+	switch val := err.(type) {
+	case stackTracer:
+		return framesFrom(val.StackTrace())
+	case framer:
+		return val.Frames()
+	default:
+		return nil
+	}
+}
+
+func framesFrom(trace errors.StackTrace) []Frame {
+	if len(trace) == 0 {
+		return nil
+	}
+	out := make([]Frame, 0, len(trace))
+	for _, val := range trace {
+		out = append(out, FrameFrom(val))
+	}
+	return out
+}
+
+func typeName(err error) string {
+	switch val := err.(type) {
+	case Val:
+		return fmt.Sprintf(`%T`, val.Val)
+	case *RemoteError:
+		return val.Type
+	default:
+		return fmt.Sprintf(`%T`, err)
+	}
+}
+
+// Strips the trailing cause message, following the convention of
+// `errors.WithMessage`.
+func ownMessage(err, cause error) string {
+	msg := err.Error()
+	if cause == nil {
+		return msg
+	}
+	own := strings.TrimSuffix(msg, cause.Error())
+	if own == msg {
+		return msg
+	}
+	return strings.TrimSuffix(own, `: `)
+}
+
+// Splits "pkg/path.Func" at the first dot after the last slash.
+func splitFunc(name string) (string, string) {
+	start := strings.LastIndexByte(name, '/') + 1
+	ind := strings.IndexByte(name[start:], '.')
+	if ind < 0 {
+		return ``, name
+	}
+	return name[:start+ind], name[start+ind+1:]
+}
@@ -51,7 +51,7 @@ func WithStack(err error) error {
 
 /*
 True if this error, or any of the errors it wraps, has a stacktrace provided by
-"github.com/pkg/errors".
+"github.com/pkg/errors", or decoded frames such as those of `RemoteError`.
 */
 func HasStack(err error) bool {
 	for {
@@ -59,10 +59,13 @@ func HasStack(err error) bool {
 			return false
 		}
 
-		// Hidden interface implemented by some types in "github.com/pkg/errors".
-		_, ok := err.(interface{ StackTrace() errors.StackTrace })
-		if ok {
+		switch val := err.(type) {
+		case stackTracer:
 			return true
+		case framer:
+			if len(val.Frames()) > 0 {
+				return true
+			}
 		}
 
 		cause := errors.Unwrap(err)
@@ -91,3 +94,30 @@ func (self Val) Unwrap() error {
 	err, _ := self.Val.(error)
 	return err
 }
+
+// Base of the error wrappers of this package. Implements unwrapping, including
+// the `Cause` method of "github.com/pkg/errors".
+type causer struct{ cause error }
+
+func (self causer) Unwrap() error { return self.cause }
+func (self causer) Cause() error  { return self.cause }
+
+/*
+Base of the wrappers which attach data to an error without changing its message
+or formatting, such as those of `Classified`. Only such wrappers are elided by
+`Links`.
+*/
+type transparent struct{ causer }
+
+func transparentOf(err error) transparent { return transparent{causer{err}} }
+
+func (self transparent) Error() string { return self.cause.Error() }
+
+func (self transparent) Format(out fmt.State, verb rune) {
+	fmt.Fprintf(out, fmt.FormatString(out, verb), self.cause)
+}
+
+func (transparent) isTransparent() {}
+
+// Implemented by embedding `transparent`.
+type transparentError interface{ isTransparent() }
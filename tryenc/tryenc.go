@@ -0,0 +1,172 @@
+/*
+Panicking helpers for the encoding packages of the standard library:
+"encoding/json", "encoding/xml", "encoding/csv" and "encoding/gob". Decoders are
+generic over the output type:
+
+	val := tryenc.DecodeJSON[User](body)
+
+Unlike `try.To(json.Unmarshal(...))`, decoding errors say where in the input
+the failure happened, via `errors.WithMessage`, for example:
+
+	failed to decode JSON into main.User at line 3, column 12, field "address.zip": json: cannot unmarshal ...
+
+The original errors, such as `*json.SyntaxError`, remain accessible via
+`errors.As`. All errors carry stacktraces via `try.To`.
+
+`ReadJSON` and `ReadXML` decode a single value and leave the rest of the input
+unread, but only for buffered readers such as `bufio.Reader`, which support
+reading one byte at a time. Other readers are read in chunks, which may consume
+the data after the value, just like the decoders of the standard library do.
+*/
+package tryenc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mitranim/try"
+	"github.com/pkg/errors"
+)
+
+// Location of a decoding failure. Zero fields are unknown.
+type position struct {
+	line   int
+	column int
+	offset int64
+	field  string
+}
+
+func (self position) String() string {
+	var out []string
+	if self.line > 0 {
+		out = append(out, `line `+strconv.Itoa(self.line)+`, column `+strconv.Itoa(self.column))
+	} else if self.offset > 0 {
+		out = append(out, `offset `+strconv.FormatInt(self.offset, 10))
+	}
+	if self.field != `` {
+		out = append(out, `field `+strconv.Quote(self.field))
+	}
+	return strings.Join(out, `, `)
+}
+
+// Panics with the error, prefixed by where and into what the decoding failed.
+func failDecode(err error, format string, typ reflect.Type, pos position) {
+	msg := `failed to decode ` + format
+	if typ != nil {
+		msg += ` into ` + typ.String()
+	}
+	if val := pos.String(); val != `` {
+		msg += ` at ` + val
+	}
+	try.To(errors.WithMessage(err, msg))
+}
+
+func failEncode(err error, format string, val interface{}) {
+	if err != nil {
+		try.To(errors.WithMessage(err, fmt.Sprintf(`failed to encode %v from %T`, format, val)))
+	}
+}
+
+func typeOf[T any]() reflect.Type { return reflect.TypeOf((*T)(nil)).Elem() }
+
+/*
+Returns the 1-based line and column of the byte at the given offset. Columns are
+counted in bytes. Offsets past the end refer to the position after the last
+byte.
+*/
+func lineColumn(src []byte, offset int64) (int, int) {
+	if offset > int64(len(src)) {
+		offset = int64(len(src))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	head := src[:offset]
+	line := bytes.Count(head, []byte{'\n'}) + 1
+	column := int(offset) - bytes.LastIndexByte(head, '\n')
+	return line, column
+}
+
+/*
+Reader which remembers the bytes read since the start of the current value, for
+computing positions of failures in streams. Memory usage is proportional to the
+size of one value plus the buffer of the decoder.
+*/
+type tracker struct {
+	src    io.Reader
+	buf    []byte
+	base   int64
+	line   int
+	column int
+}
+
+func newTracker(src io.Reader) *tracker {
+	return &tracker{src: src, line: 1, column: 1}
+}
+
+func (self *tracker) Read(out []byte) (int, error) {
+	size, err := self.src.Read(out)
+	self.buf = append(self.buf, out[:size]...)
+	return size, err
+}
+
+// Forgets the bytes before the offset, which must not exceed the amount of
+// bytes read so far.
+func (self *tracker) drop(offset int64) {
+	size := int(offset - self.base)
+	if size <= 0 {
+		return
+	}
+	for _, char := range self.buf[:size] {
+		if char == '\n' {
+			self.line++
+			self.column = 1
+		} else {
+			self.column++
+		}
+	}
+	self.buf = self.buf[:copy(self.buf, self.buf[size:])]
+	self.base = offset
+}
+
+// Position of the byte at the absolute offset, which must not precede the
+// last dropped offset.
+func (self *tracker) position(offset int64) position {
+	line, column := lineColumn(self.buf, offset-self.base)
+	if line == 1 {
+		column += self.column - 1
+	}
+	return position{line: line + self.line - 1, column: column, offset: offset}
+}
+
+// Offset of the first non-whitespace byte at or after the given offset.
+func (self *tracker) skipSpace(offset int64) int64 {
+	for offset-self.base < int64(len(self.buf)) {
+		switch self.buf[offset-self.base] {
+		case ' ', '\t', '\r', '\n':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+/*
+Reader which reads at most one byte at a time from the underlying reader, so
+that decoders which buffer their input don't consume data after the decoded
+value. Used only for readers which buffer their own input, for which this is
+cheap.
+*/
+type byteReader struct{ src io.Reader }
+
+func (self *byteReader) Read(out []byte) (int, error) {
+	if len(out) > 1 {
+		out = out[:1]
+	}
+	return self.src.Read(out)
+}
@@ -0,0 +1,63 @@
+package tryenc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/mitranim/try"
+	"github.com/pkg/errors"
+)
+
+/*
+Panicking version of `csv.Reader.ReadAll` for the given input. Errors mention
+the 1-based index of the failing record, in addition to the line and column
+reported by `*csv.ParseError`.
+*/
+func DecodeCSV(src []byte) [][]string {
+	return ReadCSV(bytes.NewReader(src))
+}
+
+// Like `DecodeCSV`, but reads from the given reader.
+func ReadCSV(src io.Reader) [][]string {
+	var out [][]string
+	eachCSV(csv.NewReader(src), func(val []string) { out = append(out, val) })
+	return out
+}
+
+/*
+Reads CSV records one by one, calling the function for each record. Panics on
+failure, with the same details as `DecodeCSV`. The slice passed to the function
+is reused between calls.
+*/
+func EachCSV(src io.Reader, fun func([]string)) {
+	reader := csv.NewReader(src)
+	reader.ReuseRecord = true
+	eachCSV(reader, fun)
+}
+
+func eachCSV(reader *csv.Reader, fun func([]string)) {
+	for ind := 1; ; ind++ {
+		val, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			try.To(errors.WithMessage(err, `failed to decode CSV at record `+strconv.Itoa(ind)))
+		}
+		fun(val)
+	}
+}
+
+// Encodes the records as CSV. Panics on failure.
+func EncodeCSV(src [][]string) []byte {
+	var buf bytes.Buffer
+	WriteCSV(&buf, src)
+	return buf.Bytes()
+}
+
+// Encodes the records as CSV into the writer. Panics on failure.
+func WriteCSV(out io.Writer, src [][]string) {
+	failEncode(csv.NewWriter(out).WriteAll(src), `CSV`, src)
+}
@@ -0,0 +1,128 @@
+package tryenc_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mitranim/try"
+	"github.com/mitranim/try/tryenc"
+	"github.com/pkg/errors"
+)
+
+type Address struct {
+	Zip int `json:"zip" xml:"zip"`
+}
+
+type User struct {
+	Name    string  `json:"name" xml:"name"`
+	Address Address `json:"address" xml:"address"`
+}
+
+func ExampleDecodeJSON() {
+	user := tryenc.DecodeJSON[User]([]byte(`{"name": "Alice"}`))
+	fmt.Println(user.Name)
+
+	err := try.Catch(func() {
+		tryenc.DecodeJSON[User]([]byte(`{
+	"name": "Bob",
+	"address": {"zip": "123"}
+}`))
+	})
+	// The message of "encoding/json" depends on the Go version.
+	fmt.Println(try.Links(err)[0].Msg)
+
+	var typeErr *json.UnmarshalTypeError
+	fmt.Println(errors.As(err, &typeErr))
+
+	err = try.Catch(func() {
+		tryenc.DecodeJSON[User]([]byte("{\n\t\"name\" \"Bob\"\n}"))
+	})
+	fmt.Println(err)
+	// Output:
+	// Alice
+	// failed to decode JSON into tryenc_test.User at line 3, column 25, field "address.zip"
+	// true
+	// failed to decode JSON into tryenc_test.User at line 2, column 9: invalid character '"' after object key
+}
+
+func ExampleEachJSON() {
+	src := `{"name": "Alice"}
+{"name": "Bob"}
+{"name": "Carol", "address": {"zip": "123"}}
+`
+	err := try.Catch(func() {
+		tryenc.EachJSON(strings.NewReader(src), func(val User) {
+			fmt.Println(val.Name)
+		})
+	})
+	fmt.Println(try.Links(err)[0].Msg)
+	// Output:
+	// Alice
+	// Bob
+	// failed to decode JSON into tryenc_test.User at line 3, column 42, field "address.zip"
+}
+
+func ExampleReadJSON() {
+	src := bufio.NewReader(strings.NewReader(`{"name": "Alice"} 123 rest`))
+	fmt.Println(tryenc.ReadJSON[User](src).Name)
+	fmt.Println(tryenc.ReadJSON[int](src))
+	fmt.Printf("%q\n", try.ByteSlice(io.ReadAll(src)))
+	// Output:
+	// Alice
+	// 123
+	// " rest"
+}
+
+func ExampleWriteJSON() {
+	var buf bytes.Buffer
+	tryenc.WriteJSON(&buf, User{Name: `Alice`})
+	tryenc.WriteJSON(&buf, User{Name: `Bob`})
+	fmt.Print(buf.String())
+
+	err := try.Catch(func() { tryenc.EncodeJSON(func() {}) })
+	fmt.Println(err)
+	// Output:
+	// {"name":"Alice","address":{"zip":0}}
+	// {"name":"Bob","address":{"zip":0}}
+	// failed to encode JSON from func(): json: unsupported type: func()
+}
+
+func ExampleDecodeXML() {
+	user := tryenc.DecodeXML[User]([]byte(`<User><name>Alice</name></User>`))
+	fmt.Println(user.Name)
+
+	err := try.Catch(func() {
+		tryenc.DecodeXML[User]([]byte("<User>\n<address><zip>abc</zip></address>\n</User>"))
+	})
+	fmt.Println(err)
+	// Output:
+	// Alice
+	// failed to decode XML into tryenc_test.User at line 2, column 24, field "address.zip": strconv.ParseInt: parsing "abc": invalid syntax
+}
+
+func ExampleDecodeCSV() {
+	fmt.Println(tryenc.DecodeCSV([]byte("one,two\nthree,four\n")))
+
+	err := try.Catch(func() {
+		tryenc.DecodeCSV([]byte("one,two\nthree\n"))
+	})
+	fmt.Println(err)
+	// Output:
+	// [[one two] [three four]]
+	// failed to decode CSV at record 2: record on line 2: wrong number of fields
+}
+
+func ExampleDecodeGob() {
+	src := tryenc.EncodeGob(User{Name: `Alice`})
+	fmt.Println(tryenc.DecodeGob[User](src).Name)
+
+	err := try.Catch(func() { tryenc.DecodeGob[int](src) })
+	fmt.Println(strings.HasPrefix(err.Error(), `failed to decode gob into int: `))
+	// Output:
+	// Alice
+	// true
+}
@@ -0,0 +1,67 @@
+package tryenc
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+
+	"github.com/mitranim/try"
+	"github.com/pkg/errors"
+)
+
+// Panicking gob decoding of a single value from the input.
+func DecodeGob[T any](src []byte) T {
+	return ReadGob[T](bytes.NewReader(src))
+}
+
+/*
+Decodes the first gob value from the reader. Panics on failure, including empty
+input. Gob data has no meaningful text positions; errors mention the output
+type.
+*/
+func ReadGob[T any](src io.Reader) (out T) {
+	err := gob.NewDecoder(src).Decode(&out)
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		failDecode(err, `gob`, typeOf[T](), position{})
+	}
+	return
+}
+
+/*
+Decodes a stream of gob values written by one encoder, calling the function for
+each value. Panics on failure, mentioning the 1-based index of the failing
+value.
+*/
+func EachGob[T any](src io.Reader, fun func(T)) {
+	dec := gob.NewDecoder(src)
+	for ind := 1; ; ind++ {
+		var val T
+		err := dec.Decode(&val)
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			try.To(errors.WithMessagef(err, `failed to decode gob into %v at value %v`, typeOf[T](), ind))
+		}
+		fun(val)
+	}
+}
+
+// Panicking gob encoding of a single value.
+func EncodeGob(val interface{}) []byte {
+	var buf bytes.Buffer
+	WriteGob(&buf, val)
+	return buf.Bytes()
+}
+
+/*
+Encodes the value as gob into the writer. Panics on failure. Each call uses a
+new encoder, which writes type information again; use `gob.Encoder` directly
+for streams decoded via `EachGob`.
+*/
+func WriteGob(out io.Writer, val interface{}) {
+	failEncode(gob.NewEncoder(out).Encode(val), `gob`, val)
+}
@@ -0,0 +1,155 @@
+package tryenc
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+/*
+Panicking version of `json.Unmarshal` that returns the decoded value. Errors
+mention the line, column and field path of the failure, if known.
+*/
+func DecodeJSON[T any](src []byte) (out T) {
+	err := json.Unmarshal(src, &out)
+	if err != nil {
+		failDecode(err, `JSON`, typeOf[T](), jsonPosition(err, func(offset int64) position {
+			line, column := lineColumn(src, offset)
+			return position{line: line, column: column, offset: offset}
+		}))
+	}
+	return
+}
+
+/*
+Decodes the first JSON value from the reader, which may contain more data
+after it. Panics on failure, including empty input, with the same details as
+`DecodeJSON`.
+
+Doesn't consume the data after the value when the reader implements
+`io.ByteScanner`, such as `bufio.Reader` or `bytes.Reader`. Such readers are
+read one byte at a time, which is cheap for them, and allow to put back the
+byte after a number, which is the only way to find where the number ends.
+Other readers are read in chunks, like with `json.Decoder`, and the data after
+the value may be consumed.
+*/
+func ReadJSON[T any](src io.Reader) T {
+	scan, _ := src.(io.ByteScanner)
+	if scan != nil {
+		src = &byteReader{src: src}
+	}
+
+	reader := newJSONReader[T](src)
+	out, ok := reader.next()
+	if !ok {
+		failDecode(io.ErrUnexpectedEOF, `JSON`, typeOf[T](), position{})
+	}
+
+	// Bytes read past the end of the value remain in the tracker.
+	if scan != nil && len(reader.track.buf) > 0 {
+		_ = scan.UnreadByte()
+	}
+	return out
+}
+
+/*
+Decodes a stream of JSON values, such as newline-delimited JSON, calling the
+function for each value. Panics on failure, with the same details as
+`DecodeJSON`, with lines counted from the start of the stream.
+*/
+func EachJSON[T any](src io.Reader, fun func(T)) {
+	reader := newJSONReader[T](src)
+	for {
+		val, ok := reader.next()
+		if !ok {
+			return
+		}
+		fun(val)
+	}
+}
+
+// Panicking version of `json.Marshal`.
+func EncodeJSON(val interface{}) []byte {
+	out, err := json.Marshal(val)
+	failEncode(err, `JSON`, val)
+	return out
+}
+
+/*
+Encodes the value as JSON into the writer, followed by a newline, which
+produces newline-delimited JSON when called repeatedly. Panics on failure.
+*/
+func WriteJSON(out io.Writer, val interface{}) {
+	failEncode(json.NewEncoder(out).Encode(val), `JSON`, val)
+}
+
+type jsonReader[T any] struct {
+	track *tracker
+	dec   *json.Decoder
+}
+
+func newJSONReader[T any](src io.Reader) jsonReader[T] {
+	track := newTracker(src)
+	return jsonReader[T]{track: track, dec: json.NewDecoder(track)}
+}
+
+func (self jsonReader[T]) next() (out T, ok bool) {
+	start := self.dec.InputOffset()
+	err := self.dec.Decode(&out)
+	if errors.Is(err, io.EOF) {
+		return out, false
+	}
+	if err != nil {
+		failDecode(err, `JSON`, typeOf[T](), self.position(err, start))
+	}
+	self.track.drop(self.dec.InputOffset())
+	return out, true
+}
+
+/*
+Offsets of syntax errors are relative to the start of the stream. Offsets of
+other errors are relative to the value, but whether that includes the preceding
+whitespace depends on the Go version. To get consistent offsets, the value,
+which the decoder has consumed, is decoded again on its own.
+*/
+func (self jsonReader[T]) position(err error, start int64) position {
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		return jsonPosition(err, self.track.position)
+	}
+
+	from := self.track.skipSpace(start)
+	src := self.track.buf[from-self.track.base : self.dec.InputOffset()-self.track.base]
+	var val T
+	return jsonPosition(json.Unmarshal(src, &val), func(offset int64) position {
+		return self.track.position(from + offset)
+	})
+}
+
+/*
+Offsets reported by "encoding/json" are the amount of bytes read before the
+failure, which includes the offending byte. The function converts the offset of
+that byte into a position.
+*/
+func jsonPosition(err error, fun func(int64) position) position {
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		return fun(lastByte(syntax.Offset))
+	}
+
+	var typ *json.UnmarshalTypeError
+	if errors.As(err, &typ) {
+		out := fun(lastByte(typ.Offset))
+		out.field = typ.Field
+		return out
+	}
+	return position{}
+}
+
+func lastByte(offset int64) int64 {
+	if offset > 0 {
+		return offset - 1
+	}
+	return 0
+}
@@ -0,0 +1,127 @@
+package tryenc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+/*
+Panicking version of `xml.Unmarshal` that returns the decoded value. Errors
+mention the line and column where the decoder stopped, and the path of the
+element being decoded, such as "address.zip", not including the root element.
+*/
+func DecodeXML[T any](src []byte) T {
+	return ReadXML[T](bytes.NewReader(src))
+}
+
+/*
+Decodes the first XML element from the reader, which may contain more data
+after it. Panics on failure, including empty input, with the same details as
+`DecodeXML`.
+
+Doesn't consume the data after the element when the reader implements
+`io.ByteReader`, such as `bufio.Reader` or `bytes.Reader`. Other readers are
+buffered by "encoding/xml", and the data after the element may be consumed.
+*/
+func ReadXML[T any](src io.Reader) T {
+	out, ok := newXMLReader[T](src).next()
+	if !ok {
+		failDecode(io.ErrUnexpectedEOF, `XML`, typeOf[T](), position{})
+	}
+	return out
+}
+
+/*
+Decodes a sequence of top-level XML elements, calling the function for each
+element. Panics on failure, with the same details as `DecodeXML`.
+*/
+func EachXML[T any](src io.Reader, fun func(T)) {
+	reader := newXMLReader[T](src)
+	for {
+		val, ok := reader.next()
+		if !ok {
+			return
+		}
+		fun(val)
+	}
+}
+
+// Panicking version of `xml.Marshal`.
+func EncodeXML(val interface{}) []byte {
+	out, err := xml.Marshal(val)
+	failEncode(err, `XML`, val)
+	return out
+}
+
+// Encodes the value as XML into the writer. Panics on failure.
+func WriteXML(out io.Writer, val interface{}) {
+	failEncode(xml.NewEncoder(out).Encode(val), `XML`, val)
+}
+
+type xmlReader[T any] struct {
+	src  *xml.Decoder
+	path *xmlPath
+	dec  *xml.Decoder
+}
+
+func newXMLReader[T any](src io.Reader) xmlReader[T] {
+	raw := xml.NewDecoder(src)
+	path := &xmlPath{src: raw}
+	return xmlReader[T]{src: raw, path: path, dec: xml.NewTokenDecoder(path)}
+}
+
+func (self xmlReader[T]) next() (out T, ok bool) {
+	err := self.dec.Decode(&out)
+	if errors.Is(err, io.EOF) {
+		return out, false
+	}
+	if err != nil {
+		line, column := self.src.InputPos()
+		failDecode(err, `XML`, typeOf[T](), position{
+			line:   line,
+			column: column,
+			offset: self.src.InputOffset(),
+			field:  self.path.String(),
+		})
+	}
+	return out, true
+}
+
+/*
+Token reader which tracks the path of the current element. An element stays in
+the path until the token after its end, because the decoder converts the text
+of an element after reading its end.
+*/
+type xmlPath struct {
+	src   xml.TokenReader
+	names []string
+	ended bool
+}
+
+func (self *xmlPath) Token() (xml.Token, error) {
+	if self.ended {
+		self.names = self.names[:len(self.names)-1]
+		self.ended = false
+	}
+
+	tok, err := self.src.Token()
+	switch tok := tok.(type) {
+	case xml.StartElement:
+		self.names = append(self.names, tok.Name.Local)
+	case xml.EndElement:
+		self.ended = len(self.names) > 0
+	}
+	return tok, err
+}
+
+// Names of the current element and its ancestors, excluding the root.
+func (self *xmlPath) String() string {
+	if len(self.names) <= 1 {
+		return ``
+	}
+	return strings.Join(self.names[1:], `.`)
+}
@@ -0,0 +1,290 @@
+/*
+Panicking helpers for running commands via "os/exec", for scripts in the
+"exceptions" style:
+
+	defer try.Detail(`failed to deploy`)
+	tryexec.Run(`git`, `push`, `origin`)
+	rev := tryexec.Output(`git`, `rev-parse`, `HEAD`)
+
+Failures panic with an `*Error` which includes the command line, the exit code
+and the tail of stderr, so that messages read like shell traces:
+
+	failed to deploy: $ git push origin: exit code 128
+	fatal: 'origin' does not appear to be a git repository
+
+All errors carry stacktraces via `try.To`.
+*/
+package tryexec
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/mitranim/try"
+	"github.com/pkg/errors"
+)
+
+// Maximum amount of trailing bytes of stderr stored in `Error`.
+const StderrLimit = 4096
+
+// Maximum amount of trailing lines of stderr printed by `Error.Error`.
+const StderrLines = 10
+
+// Runs the command. Panics on failure. See `RunCmd`.
+func Run(name string, args ...string) {
+	RunCmd(exec.Command(name, args...))
+}
+
+// Like `Run`, but kills the command when the context is done.
+func RunContext(ctx context.Context, name string, args ...string) {
+	run(ctx, exec.CommandContext(ctx, name, args...))
+}
+
+/*
+Runs the command and waits for it to finish. Panics with an `*Error` on
+failure. Captures the tail of stderr for the error; if `cmd.Stderr` is set, it
+still receives the full output.
+*/
+func RunCmd(cmd *exec.Cmd) {
+	run(nil, cmd)
+}
+
+// Runs the command and returns its stdout. Panics on failure. See `OutputCmd`.
+func Output(name string, args ...string) []byte {
+	return OutputCmd(exec.Command(name, args...))
+}
+
+// Like `Output`, but kills the command when the context is done.
+func OutputContext(ctx context.Context, name string, args ...string) []byte {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	run(ctx, cmd)
+	return buf.Bytes()
+}
+
+/*
+Runs the command and returns its stdout, which must not be already set. Panics
+with an `*Error` on failure, like `RunCmd`.
+*/
+func OutputCmd(cmd *exec.Cmd) []byte {
+	if cmd.Stdout != nil {
+		try.To(errors.New(`tryexec: stdout already set`))
+	}
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	run(nil, cmd)
+	return buf.Bytes()
+}
+
+/*
+Runs the commands as a shell pipeline, connecting the stdout of each command to
+the stdin of the next one, and returns the stdout of the last command, unless
+it's already set. Waits for all commands. Like the "pipefail" option of Bash,
+panics if any command fails, with the error of the first failed command.
+
+If a command fails to start, the commands already started are killed and
+waited for before panicking.
+*/
+func Pipe(cmds ...*exec.Cmd) []byte {
+	if len(cmds) == 0 {
+		return nil
+	}
+	for _, cmd := range cmds[:len(cmds)-1] {
+		if cmd.Stdout != nil {
+			try.To(errors.New(`tryexec: stdout already set`))
+		}
+	}
+
+	// Pipe ends held by this process, per command. Each command's ends are
+	// closed once it has started, so that only the commands hold them; this lets
+	// readers see EOF and writers see a broken pipe.
+	ends := make([][]*os.File, len(cmds))
+	defer func() {
+		for _, files := range ends {
+			closeFiles(files)
+		}
+	}()
+
+	for ind, cmd := range cmds[:len(cmds)-1] {
+		read, write, err := os.Pipe()
+		try.To(err)
+		cmd.Stdout = write
+		cmds[ind+1].Stdin = read
+		ends[ind] = append(ends[ind], write)
+		ends[ind+1] = append(ends[ind+1], read)
+	}
+
+	var buf bytes.Buffer
+	last := cmds[len(cmds)-1]
+	if last.Stdout == nil {
+		last.Stdout = &buf
+	}
+
+	tails := make([]*tail, len(cmds))
+	var started []*exec.Cmd
+	var fail error
+
+	for ind, cmd := range cmds {
+		tails[ind] = capture(cmd)
+		err := cmd.Start()
+		closeFiles(ends[ind])
+		ends[ind] = nil
+
+		if err != nil {
+			fail = newError(nil, cmd, tails[ind], err)
+			for _, cmd := range started {
+				_ = cmd.Process.Kill()
+			}
+			break
+		}
+		started = append(started, cmd)
+	}
+
+	for _, files := range ends {
+		closeFiles(files)
+	}
+	ends = nil
+
+	for ind, cmd := range started {
+		err := cmd.Wait()
+		if err != nil && fail == nil {
+			fail = newError(nil, cmd, tails[ind], err)
+		}
+	}
+
+	try.To(fail)
+	return buf.Bytes()
+}
+
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		_ = file.Close()
+	}
+}
+
+func run(ctx context.Context, cmd *exec.Cmd) {
+	tail := capture(cmd)
+	err := cmd.Run()
+	if err != nil {
+		try.To(newError(ctx, cmd, tail, err))
+	}
+}
+
+func capture(cmd *exec.Cmd) *tail {
+	out := &tail{}
+	if cmd.Stderr == nil {
+		cmd.Stderr = out
+	} else {
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, out)
+	}
+	return out
+}
+
+func newError(ctx context.Context, cmd *exec.Cmd, tail *tail, err error) *Error {
+	out := &Error{
+		Args:   cmd.Args,
+		Code:   -1,
+		Stderr: string(tail.buf),
+		Err:    err,
+	}
+
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		out.Code = exit.ExitCode()
+	}
+	if ctx != nil && ctx.Err() != nil {
+		out.Err = ctx.Err()
+	}
+	return out
+}
+
+/*
+Error of a failed command. `Code` is the exit code, or -1 if the command
+didn't start or was killed by a signal. `Stderr` is the tail of stderr, up to
+`StderrLimit`. `Err` is the underlying error, such as `*exec.ExitError`, or the
+error of the context if the command was killed due to its cancellation.
+*/
+type Error struct {
+	Args   []string
+	Code   int
+	Stderr string
+	Err    error
+}
+
+/*
+Implement `error`. The first line has the command line and the exit code or the
+underlying error, and is followed by the last `StderrLines` lines of stderr.
+*/
+func (self *Error) Error() string {
+	var buf strings.Builder
+	buf.WriteString(`$ `)
+	buf.WriteString(CommandLine(self.Args...))
+	buf.WriteString(`: `)
+
+	var exit *exec.ExitError
+	if self.Code >= 0 && errors.As(self.Err, &exit) {
+		buf.WriteString(`exit code `)
+		buf.WriteString(strconv.Itoa(self.Code))
+	} else if self.Err != nil {
+		buf.WriteString(self.Err.Error())
+	}
+
+	lines := strings.Split(strings.TrimRight(self.Stderr, "\n"), "\n")
+	if len(lines) > StderrLines {
+		lines = lines[len(lines)-StderrLines:]
+	}
+	for _, line := range lines {
+		if strings.TrimSpace(line) != `` {
+			buf.WriteString("\n")
+			buf.WriteString(strings.TrimRight(line, "\r"))
+		}
+	}
+	return buf.String()
+}
+
+// Implement error unwrapping.
+func (self *Error) Unwrap() error { return self.Err }
+
+/*
+Formats the arguments like a shell command line, quoting them with single
+quotes where necessary. Useful for logging commands before running them.
+*/
+func CommandLine(args ...string) string {
+	out := make([]string, len(args))
+	for ind, arg := range args {
+		out[ind] = quote(arg)
+	}
+	return strings.Join(out, ` `)
+}
+
+func quote(src string) string {
+	if src == `` {
+		return `''`
+	}
+	if strings.IndexFunc(src, isUnsafe) < 0 {
+		return src
+	}
+	return `'` + strings.ReplaceAll(src, `'`, `'\''`) + `'`
+}
+
+func isUnsafe(char rune) bool {
+	return !(char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' ||
+		char >= '0' && char <= '9' || strings.ContainsRune(`-_./=:,+@%`, char))
+}
+
+// Writer keeping only the last `StderrLimit` bytes.
+type tail struct{ buf []byte }
+
+func (self *tail) Write(src []byte) (int, error) {
+	self.buf = append(self.buf, src...)
+	if len(self.buf) > StderrLimit {
+		self.buf = self.buf[:copy(self.buf, self.buf[len(self.buf)-StderrLimit:])]
+	}
+	return len(src), nil
+}
@@ -0,0 +1,86 @@
+package tryexec_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/mitranim/try"
+	"github.com/mitranim/try/tryexec"
+)
+
+func ExampleRun() {
+	err := try.Catch(func() {
+		defer try.Detail(`failed to deploy`)
+		tryexec.Run(`sh`, `-c`, `echo 'fatal: no remote' >&2; exit 3`)
+	})
+	fmt.Println(err)
+
+	var exit *tryexec.Error
+	fmt.Println(errors.As(err, &exit), exit.Code)
+	fmt.Println(try.HasStack(err))
+	// Output:
+	// failed to deploy: $ sh -c 'echo '\''fatal: no remote'\'' >&2; exit 3': exit code 3
+	// fatal: no remote
+	// true 3
+	// true
+}
+
+func ExampleOutput() {
+	fmt.Print(string(tryexec.Output(`echo`, `hello world`)))
+
+	err := try.Catch(func() {
+		tryexec.Output(`tryexec-missing-command`)
+	})
+	fmt.Println(errors.Is(err, exec.ErrNotFound))
+	// Output:
+	// hello world
+	// true
+}
+
+func ExampleRunContext() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := try.Catch(func() {
+		tryexec.RunContext(ctx, `sleep`, `10`)
+	})
+	fmt.Println(err)
+	fmt.Println(errors.Is(err, context.DeadlineExceeded))
+	// Output:
+	// $ sleep 10: context deadline exceeded
+	// true
+}
+
+func ExamplePipe() {
+	out := tryexec.Pipe(
+		exec.Command(`printf`, `b\na\nc\n`),
+		exec.Command(`sort`),
+		exec.Command(`head`, `-n`, `2`),
+	)
+	fmt.Print(string(out))
+
+	err := try.Catch(func() {
+		tryexec.Pipe(
+			exec.Command(`sh`, `-c`, `echo first failed >&2; exit 1`),
+			exec.Command(`cat`),
+		)
+	})
+	fmt.Println(err)
+	// Output:
+	// a
+	// b
+	// $ sh -c 'echo first failed >&2; exit 1': exit code 1
+	// first failed
+}
+
+func ExampleCommandLine() {
+	fmt.Println(tryexec.CommandLine(`git`, `commit`, `-m`, `it's done`, ``))
+	fmt.Println(strings.Count(tryexec.CommandLine(`ls`, `-la`), `'`))
+	// Output:
+	// git commit -m 'it'\''s done' ''
+	// 0
+}
@@ -0,0 +1,46 @@
+package tryexec_test
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/mitranim/try"
+	"github.com/mitranim/try/tryexec"
+	"github.com/pkg/errors"
+)
+
+func TestPipe_startFailure(t *testing.T) {
+	err := withTimeout(t, func() {
+		tryexec.Pipe(exec.Command(`yes`), exec.Command(`no-such-command-xyz`))
+	})
+	if !errors.Is(err, exec.ErrNotFound) {
+		t.Fatalf("expected exec.ErrNotFound, got %v", err)
+	}
+}
+
+func TestPipe_earlyExit(t *testing.T) {
+	var out []byte
+	err := withTimeout(t, func() {
+		out = tryexec.Pipe(exec.Command(`yes`), exec.Command(`head`, `-n`, `1`))
+	})
+
+	// Like Bash with "pipefail", the writer killed by SIGPIPE fails the pipeline.
+	if err == nil {
+		t.Fatalf("expected the writer to fail with a broken pipe, got %q", out)
+	}
+}
+
+func withTimeout(t *testing.T, fun func()) error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- try.Catch(fun) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(10 * time.Second):
+		t.Fatal(`the pipeline didn't finish`)
+		return nil
+	}
+}
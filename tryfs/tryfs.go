@@ -0,0 +1,195 @@
+/*
+Panicking versions of common file-system operations, for the "exceptions" style.
+Replaces `try.To(os.MkdirAll(...))` and `try.ByteSlice(os.ReadFile(...))` with
+`tryfs.MkdirAll(...)` and `tryfs.ReadFile(...)`, and covers results such as
+`*os.File` which have no typed "try" function.
+
+All errors are `*fs.PathError` carrying the operation and the path, with a
+stacktrace via `try.To`. Errors of the "os" package already have this form;
+other errors, such as those of custom `fs.FS` implementations, are wrapped.
+Use `errors.Is` with `fs.ErrNotExist` and similar to check specific failures.
+*/
+package tryfs
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/mitranim/try"
+	"github.com/pkg/errors"
+)
+
+// Panicking version of `os.Open`.
+func Open(path string) *os.File {
+	file, err := os.Open(path)
+	check(`open`, path, err)
+	return file
+}
+
+// Panicking version of `os.Create`.
+func Create(path string) *os.File {
+	file, err := os.Create(path)
+	check(`open`, path, err)
+	return file
+}
+
+// Panicking version of `os.OpenFile`.
+func OpenFile(path string, flag int, perm fs.FileMode) *os.File {
+	file, err := os.OpenFile(path, flag, perm)
+	check(`open`, path, err)
+	return file
+}
+
+// Panicking version of `os.ReadFile`.
+func ReadFile(path string) []byte {
+	body, err := os.ReadFile(path)
+	check(`read`, path, err)
+	return body
+}
+
+// Panicking version of `os.WriteFile`.
+func WriteFile(path string, body []byte, perm fs.FileMode) {
+	check(`write`, path, os.WriteFile(path, body, perm))
+}
+
+// Panicking version of `os.ReadDir`.
+func ReadDir(path string) []fs.DirEntry {
+	out, err := os.ReadDir(path)
+	check(`readdir`, path, err)
+	return out
+}
+
+// Panicking version of `os.Stat`.
+func Stat(path string) fs.FileInfo {
+	out, err := os.Stat(path)
+	check(`stat`, path, err)
+	return out
+}
+
+/*
+Similar to `Stat`, but returns nil if the file doesn't exist, instead of
+panicking. Other errors, such as lack of permissions, still cause a panic.
+*/
+func StatOpt(path string) fs.FileInfo {
+	out, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	check(`stat`, path, err)
+	return out
+}
+
+// Panicking version of `os.Mkdir`.
+func Mkdir(path string, perm fs.FileMode) {
+	check(`mkdir`, path, os.Mkdir(path, perm))
+}
+
+// Panicking version of `os.MkdirAll`.
+func MkdirAll(path string, perm fs.FileMode) {
+	check(`mkdir`, path, os.MkdirAll(path, perm))
+}
+
+// Panicking version of `os.Remove`.
+func Remove(path string) {
+	check(`remove`, path, os.Remove(path))
+}
+
+// Panicking version of `os.RemoveAll`.
+func RemoveAll(path string) {
+	check(`remove`, path, os.RemoveAll(path))
+}
+
+/*
+Panicking version of `os.Rename`. Errors are `*os.LinkError` rather than
+`*fs.PathError`, and mention both paths.
+*/
+func Rename(src, tar string) {
+	try.To(os.Rename(src, tar))
+}
+
+/*
+Panicking version of `filepath.WalkDir`. The function is called for each file
+and directory, and may panic with `fs.SkipDir` or `fs.SkipAll` to control the
+walk, as it would return them in the original. Other panics abort the walk and
+propagate to the caller. Errors of reading directories cause a panic.
+*/
+func WalkDir(root string, fun func(path string, entry fs.DirEntry)) {
+	check(`walk`, root, filepath.WalkDir(root, walker(fun)))
+}
+
+// Panicking version of `fs.ReadFile`.
+func ReadFileFS(fsys fs.FS, path string) []byte {
+	body, err := fs.ReadFile(fsys, path)
+	check(`read`, path, err)
+	return body
+}
+
+// Panicking version of `fs.ReadDir`.
+func ReadDirFS(fsys fs.FS, path string) []fs.DirEntry {
+	out, err := fs.ReadDir(fsys, path)
+	check(`readdir`, path, err)
+	return out
+}
+
+// Panicking version of `fs.Stat`.
+func StatFS(fsys fs.FS, path string) fs.FileInfo {
+	out, err := fs.Stat(fsys, path)
+	check(`stat`, path, err)
+	return out
+}
+
+// Panicking version of `fs.Sub`.
+func SubFS(fsys fs.FS, dir string) fs.FS {
+	out, err := fs.Sub(fsys, dir)
+	check(`sub`, dir, err)
+	return out
+}
+
+// Panicking version of `fs.WalkDir`. See `WalkDir` for the callback.
+func WalkDirFS(fsys fs.FS, root string, fun func(path string, entry fs.DirEntry)) {
+	check(`walk`, root, fs.WalkDir(fsys, root, walker(fun)))
+}
+
+/*
+Adapts a panicking callback to `fs.WalkDirFunc`. The walk functions compare
+the returned errors to `fs.SkipDir` and `fs.SkipAll` by equality, which
+requires unwrapping the recovered panics.
+*/
+func walker(fun func(string, fs.DirEntry)) fs.WalkDirFunc {
+	return func(path string, entry fs.DirEntry, err error) (out error) {
+		if err != nil {
+			return err
+		}
+		defer unwrapSkip(&out)
+		defer try.RecOnly(&out, isSkip)
+		fun(path, entry)
+		return nil
+	}
+}
+
+// Must be deferred before `try.RecOnly`, so that it runs after the recovery.
+func unwrapSkip(ptr *error) {
+	switch {
+	case errors.Is(*ptr, fs.SkipAll):
+		*ptr = fs.SkipAll
+	case errors.Is(*ptr, fs.SkipDir):
+		*ptr = fs.SkipDir
+	}
+}
+
+func isSkip(err error) bool {
+	return errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll)
+}
+
+// Panics with a `*fs.PathError`, wrapping the error unless it already is one.
+func check(op, path string, err error) {
+	if err == nil {
+		return
+	}
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) {
+		err = &fs.PathError{Op: op, Path: path, Err: err}
+	}
+	try.To(err)
+}
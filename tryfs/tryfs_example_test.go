@@ -0,0 +1,83 @@
+package tryfs_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+
+	"github.com/mitranim/try"
+	"github.com/mitranim/try/tryfs"
+)
+
+func ExampleReadFile() {
+	dir := tempDir()
+	defer tryfs.RemoveAll(dir)
+
+	path := filepath.Join(dir, `file.txt`)
+	tryfs.WriteFile(path, []byte(`hello world`), 0666)
+	fmt.Println(string(tryfs.ReadFile(path)))
+
+	err := try.Catch(func() {
+		tryfs.ReadFile(filepath.Join(dir, `missing.txt`))
+	})
+	fmt.Println(errors.Is(err, fs.ErrNotExist))
+	fmt.Println(try.HasStack(err))
+	// Output:
+	// hello world
+	// true
+	// true
+}
+
+func ExampleStatOpt() {
+	dir := tempDir()
+	defer tryfs.RemoveAll(dir)
+
+	fmt.Println(tryfs.StatOpt(filepath.Join(dir, `missing.txt`)) == nil)
+	fmt.Println(tryfs.StatOpt(dir).IsDir())
+	// Output:
+	// true
+	// true
+}
+
+func ExampleWalkDir() {
+	dir := tempDir()
+	defer tryfs.RemoveAll(dir)
+
+	tryfs.MkdirAll(filepath.Join(dir, `one`, `two`), 0777)
+	tryfs.MkdirAll(filepath.Join(dir, `skip`), 0777)
+	tryfs.WriteFile(filepath.Join(dir, `one`, `two`, `file.txt`), nil, 0666)
+	tryfs.WriteFile(filepath.Join(dir, `skip`, `file.txt`), nil, 0666)
+
+	tryfs.WalkDir(dir, func(path string, entry fs.DirEntry) {
+		if entry.Name() == `skip` {
+			panic(fs.SkipDir)
+		}
+		fmt.Println(filepath.ToSlash(try.String(filepath.Rel(dir, path))))
+	})
+	// Output:
+	// .
+	// one
+	// one/two
+	// one/two/file.txt
+}
+
+func ExampleReadFileFS() {
+	fsys := fstest.MapFS{`dir/file.txt`: {Data: []byte(`hello world`)}}
+
+	fmt.Println(string(tryfs.ReadFileFS(tryfs.SubFS(fsys, `dir`), `file.txt`)))
+
+	err := try.Catch(func() {
+		tryfs.ReadFileFS(fsys, `missing.txt`)
+	})
+	fmt.Println(err)
+	// Output:
+	// hello world
+	// open missing.txt: file does not exist
+}
+
+func tempDir() string {
+	return try.String(os.MkdirTemp(``, `tryfs`))
+}
@@ -0,0 +1,245 @@
+/*
+HTTP tools for the "exceptions" style. `Recoverer` is a middleware that turns
+handler panics into RFC 7807 "application/problem+json" responses, with status
+codes chosen by a `Registry`:
+
+	reg := new(tryhttp.Registry)
+	reg.Is(sql.ErrNoRows, http.StatusNotFound)
+	reg.As(new(*ValidationError), http.StatusBadRequest)
+
+	handler = tryhttp.Recoverer{Registry: reg, Report: report}.Wrap(handler)
+
+Handlers can then simply panic, for example via `try.To`.
+*/
+package tryhttp
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"reflect"
+
+	"github.com/mitranim/try"
+	"github.com/pkg/errors"
+)
+
+/*
+Maps errors to HTTP status codes. Rules are checked in the order of
+registration, and the first match wins. Errors not matching any rule produce
+503 if they're retryable according to `try.IsRetryable`, and 500 otherwise.
+
+The zero value is ready to use. Rules must be registered before use; a registry
+is safe for concurrent reads, but not for concurrent modification.
+*/
+type Registry struct {
+	rules []rule
+}
+
+type rule struct {
+	test   func(error) bool
+	status int
+}
+
+// Maps errors matching the target via `errors.Is` to the status.
+func (self *Registry) Is(target error, status int) {
+	self.Func(func(err error) bool { return errors.Is(err, target) }, status)
+}
+
+/*
+Maps errors matching the target via `errors.As` to the status. Like in
+`errors.As`, the target must be a non-nil pointer to an interface or to a type
+implementing `error`, for example `new(*os.PathError)`. It's used only for its
+type, and never modified.
+*/
+func (self *Registry) As(target interface{}, status int) {
+	typ := reflect.TypeOf(target)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		panic(errors.Errorf(`expected a non-nil pointer, got %T`, target))
+	}
+	typ = typ.Elem()
+	if typ.Kind() != reflect.Interface && !typ.Implements(errorType) {
+		panic(errors.Errorf(`expected a pointer to an interface or an error type, got %T`, target))
+	}
+	self.Func(func(err error) bool {
+		return errors.As(err, reflect.New(typ).Interface())
+	}, status)
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Maps errors satisfying the test to the status.
+func (self *Registry) Func(test func(error) bool, status int) {
+	self.rules = append(self.rules, rule{test, status})
+}
+
+// Status for the error: the status of the first matching rule, or 503 or 500.
+func (self *Registry) Status(err error) int {
+	if self != nil {
+		for _, rule := range self.rules {
+			if rule.test(err) {
+				return rule.status
+			}
+		}
+	}
+	if try.IsRetryable(err) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+/*
+Response body in the RFC 7807 "application/problem+json" format. `Stack` is an
+extension, included only in debug mode.
+*/
+type Problem struct {
+	Type   string      `json:"type"`
+	Title  string      `json:"title"`
+	Status int         `json:"status"`
+	Detail string      `json:"detail,omitempty"`
+	Stack  []try.Frame `json:"stack,omitempty"`
+}
+
+/*
+HTTP middleware that recovers panics of the wrapped handler and responds with a
+`Problem`. Panic values are converted via `try.Err`.
+
+The problem's detail is the public message of the error, if any, attached via
+`try.DetailPublic` or implied by `try.Classify`; see `try.HasPublicMessage`.
+Other messages, even of client errors (4xx), tend to describe internals, such
+as file paths of `*os.PathError`, and are included only when opted into via
+`ClientDetail` or `Debug`. Stacktraces are never included unless `Debug` is
+set.
+
+Panics with `http.ErrAbortHandler` are re-panicked unchanged, letting
+"net/http" abort the response silently. If the handler has already started
+writing the response, it's too late to replace it; the error is reported, and
+the response is aborted the same way, so that clients don't mistake a
+truncated response for a complete one.
+*/
+type Recoverer struct {
+	// Maps errors to status codes. When nil, errors produce 503 or 500, see
+	// `Registry`.
+	Registry *Registry
+
+	// Include the full error message in client error (4xx) responses when the
+	// error has no public message. Use only when all such errors are known to
+	// have messages safe for clients.
+	ClientDetail bool
+
+	// Include the full error message and the stacktrace in all responses.
+	// Must not be used in production.
+	Debug bool
+
+	// Called with every recovered error other than `http.ErrAbortHandler`,
+	// before writing the response. Optional.
+	Report func(*http.Request, error)
+}
+
+// Returns a handler which serves via the given handler, recovering its panics.
+func (self Recoverer) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rew http.ResponseWriter, req *http.Request) {
+		out := &writer{ResponseWriter: rew}
+		defer self.recover(out, req)
+		next.ServeHTTP(out, req)
+	})
+}
+
+// Must be deferred.
+func (self Recoverer) recover(rew *writer, req *http.Request) {
+	val := recover()
+	if val == nil {
+		return
+	}
+
+	err := try.Err(val)
+	if errors.Is(err, http.ErrAbortHandler) {
+		panic(http.ErrAbortHandler)
+	}
+
+	if self.Report != nil {
+		self.Report(req, err)
+	}
+	if rew.wrote {
+		panic(http.ErrAbortHandler)
+	}
+	self.Respond(rew, err)
+}
+
+/*
+Writes the problem response for the error, as the middleware would. Useful for
+errors caught without panicking.
+*/
+func (self Recoverer) Respond(rew http.ResponseWriter, err error) {
+	problem := self.Problem(err)
+	head := rew.Header()
+	head.Set(`Content-Type`, `application/problem+json`)
+	head.Set(`X-Content-Type-Options`, `nosniff`)
+	rew.WriteHeader(problem.Status)
+	_ = json.NewEncoder(rew).Encode(problem)
+}
+
+// Returns the problem describing the error. See `Recoverer` for the rules.
+func (self Recoverer) Problem(err error) Problem {
+	status := self.Registry.Status(err)
+	out := Problem{
+		Type:   `about:blank`,
+		Title:  http.StatusText(status),
+		Status: status,
+	}
+	if self.Debug {
+		out.Detail = err.Error()
+	} else if try.HasPublicMessage(err) {
+		out.Detail = try.PublicMessage(err)
+	} else if self.ClientDetail && status < http.StatusInternalServerError {
+		out.Detail = err.Error()
+	}
+	if self.Debug {
+		out.Stack = try.StackOf(err)
+	}
+	return out
+}
+
+/*
+Tracks whether the response was started. Supports `http.Flusher` and
+`http.Hijacker` when the underlying writer does, and `http.ResponseController`
+via `Unwrap`.
+*/
+type writer struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+// Informational responses such as "103 Early Hints" don't start the response.
+func (self *writer) WriteHeader(status int) {
+	if status >= http.StatusOK {
+		self.wrote = true
+	}
+	self.ResponseWriter.WriteHeader(status)
+}
+
+func (self *writer) Write(src []byte) (int, error) {
+	self.wrote = true
+	return self.ResponseWriter.Write(src)
+}
+
+func (self *writer) Unwrap() http.ResponseWriter { return self.ResponseWriter }
+
+// Implement `http.Flusher`. Flushing starts the response.
+func (self *writer) Flush() {
+	self.wrote = true
+	_ = http.NewResponseController(self.ResponseWriter).Flush()
+}
+
+/*
+Implement `http.Hijacker`. Returns `http.ErrNotSupported` when the underlying
+writer doesn't support hijacking. After hijacking, the connection belongs to
+the handler, and the response can't be replaced.
+*/
+func (self *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(self.ResponseWriter).Hijack()
+	if err == nil {
+		self.wrote = true
+	}
+	return conn, buf, err
+}
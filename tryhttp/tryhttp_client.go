@@ -0,0 +1,193 @@
+package tryhttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mitranim/try"
+	"github.com/mitranim/try/tryenc"
+)
+
+/*
+Panicking HTTP client. Wraps `*http.Client`, treating responses with non-2xx
+status codes as errors. Failures panic with a stacktrace via `try.To`. Non-2xx
+responses panic with a `*StatusError`, use `errors.As` to inspect it. The zero
+value is ready to use.
+*/
+type Client struct {
+	// Underlying client. When nil, `http.DefaultClient` is used.
+	Client *http.Client
+
+	// Headers added to each request, unless already present in the request.
+	// The given request is not modified; a copy is sent instead.
+	Header http.Header
+
+	// Maximum amount of bytes of the response body stored in `StatusError`.
+	// Zero means 1024. Negative means none.
+	BodyLimit int
+}
+
+/*
+Sends the request and returns the response if the status code is 2xx. The
+caller must close the body. For other status codes, reads the body up to
+`BodyLimit`, closes it, and panics with a `*StatusError`.
+*/
+func (self Client) Do(req *http.Request) *http.Response {
+	req = self.withHeader(req)
+
+	res, err := self.client().Do(req)
+	try.To(err)
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res
+	}
+
+	defer res.Body.Close()
+	try.To(self.statusError(req, res))
+	return nil
+}
+
+// Copies the request, adding the default headers which it doesn't have.
+func (self Client) withHeader(req *http.Request) *http.Request {
+	if len(self.Header) == 0 {
+		return req
+	}
+
+	req = req.Clone(req.Context())
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	for key, vals := range self.Header {
+		if _, ok := req.Header[key]; !ok {
+			req.Header[key] = append([]string(nil), vals...)
+		}
+	}
+	return req
+}
+
+func (self Client) client() *http.Client {
+	if self.Client != nil {
+		return self.Client
+	}
+	return http.DefaultClient
+}
+
+func (self Client) statusError(req *http.Request, res *http.Response) *StatusError {
+	limit := self.BodyLimit
+	if limit == 0 {
+		limit = 1024
+	}
+
+	out := &StatusError{
+		Status: res.StatusCode,
+		Method: req.Method,
+		URL:    req.URL.Redacted(),
+	}
+	if limit > 0 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, int64(limit)+1))
+		if len(body) > limit {
+			body = body[:limit]
+			out.Truncated = true
+		}
+		out.Body = string(body)
+	}
+	return out
+}
+
+// Shortcut for `GetJSONContext` with `context.Background`.
+func GetJSON[T any](client Client, url string) T {
+	return GetJSONContext[T](context.Background(), client, url)
+}
+
+/*
+Sends a GET request and decodes the JSON response via `tryenc.ReadJSON`.
+Returns the zero value for "204 No Content". Always closes the response body.
+Panics on failure, including non-2xx status codes, see `Client.Do`.
+*/
+func GetJSONContext[T any](ctx context.Context, client Client, url string) T {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	try.To(err)
+	req.Header.Set(`Accept`, `application/json`)
+	return doJSON[T](client, req)
+}
+
+// Shortcut for `PostJSONContext` with `context.Background`.
+func PostJSON[T any](client Client, url string, body interface{}) T {
+	return PostJSONContext[T](context.Background(), client, url, body)
+}
+
+/*
+Sends a POST request with the body encoded as JSON, and decodes the JSON
+response like `GetJSONContext`.
+*/
+func PostJSONContext[T any](ctx context.Context, client Client, url string, body interface{}) T {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(tryenc.EncodeJSON(body)))
+	try.To(err)
+	req.Header.Set(`Accept`, `application/json`)
+	req.Header.Set(`Content-Type`, `application/json`)
+	return doJSON[T](client, req)
+}
+
+func doJSON[T any](client Client, req *http.Request) (out T) {
+	res := client.Do(req)
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNoContent {
+		return
+	}
+	return tryenc.ReadJSON[T](res.Body)
+}
+
+/*
+Error of a response with a non-2xx status code. `Body` contains the beginning
+of the response body, up to `Client.BodyLimit`, and `Truncated` indicates that
+the body was longer. `URL` omits the password, if any.
+*/
+type StatusError struct {
+	Status    int    `json:"status"`
+	Method    string `json:"method"`
+	URL       string `json:"url"`
+	Body      string `json:"body,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+/*
+True for status codes which indicate a transient failure: 408, 429, 502, 503
+and 504. Used by `try.IsRetryable`.
+*/
+func (self *StatusError) Retryable() bool {
+	switch self.Status {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// Implement `error`. Includes the text of the body, if any, collapsed into one
+// line and shortened.
+func (self *StatusError) Error() string {
+	out := fmt.Sprintf(`%v %v: %v %v`, self.Method, self.URL, self.Status, http.StatusText(self.Status))
+
+	body := strings.Join(strings.Fields(self.Body), ` `)
+	if body == `` || !utf8.ValidString(body) {
+		return out
+	}
+
+	cut := self.Truncated
+	if len(body) > 256 {
+		body = body[:256]
+		for !utf8.ValidString(body) {
+			body = body[:len(body)-1]
+		}
+		cut = true
+	}
+	if cut {
+		body += `…`
+	}
+	return out + `: ` + body
+}
@@ -0,0 +1,261 @@
+package tryhttp_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/mitranim/try"
+	"github.com/mitranim/try/tryhttp"
+	"github.com/pkg/errors"
+)
+
+func ExampleRecoverer() {
+	reg := new(tryhttp.Registry)
+	reg.Is(sql.ErrNoRows, http.StatusNotFound)
+	reg.As(new(*os.PathError), http.StatusBadRequest)
+
+	rec := tryhttp.Recoverer{
+		Registry: reg,
+		Report: func(req *http.Request, err error) {
+			fmt.Printf("reported: %v %v\n", req.URL.Path, err)
+		},
+	}
+
+	handler := rec.Wrap(http.HandlerFunc(func(rew http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case `/missing`:
+			try.To(try.Public(sql.ErrNoRows, `user not found`))
+		case `/path`:
+			try.ByteSlice(os.ReadFile(`/non/existent`))
+		case `/internal`:
+			panic(`secret internal state`)
+		}
+		fmt.Fprint(rew, `ok`)
+	}))
+
+	for _, path := range []string{`/`, `/missing`, `/path`, `/internal`} {
+		rew := httptest.NewRecorder()
+		handler.ServeHTTP(rew, httptest.NewRequest(http.MethodGet, path, nil))
+		fmt.Println(rew.Code, rew.Header().Get(`Content-Type`), strings.TrimSpace(rew.Body.String()))
+	}
+	// Output:
+	// 200 text/plain; charset=utf-8 ok
+	// reported: /missing sql: no rows in result set
+	// 404 application/problem+json {"type":"about:blank","title":"Not Found","status":404,"detail":"user not found"}
+	// reported: /path open /non/existent: no such file or directory
+	// 400 application/problem+json {"type":"about:blank","title":"Bad Request","status":400}
+	// reported: /internal secret internal state
+	// 500 application/problem+json {"type":"about:blank","title":"Internal Server Error","status":500}
+}
+
+func ExampleRecoverer_debug() {
+	handler := tryhttp.Recoverer{Debug: true}.Wrap(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(`failure`)
+	}))
+
+	rew := httptest.NewRecorder()
+	handler.ServeHTTP(rew, httptest.NewRequest(http.MethodGet, `/`, nil))
+
+	problem := try.Interface(decodeProblem(rew)).(tryhttp.Problem)
+	fmt.Println(problem.Status, problem.Detail, len(problem.Stack) > 0)
+	// Output:
+	// 500 failure true
+}
+
+func ExampleRecoverer_userFacing() {
+	handler := tryhttp.Recoverer{}.Wrap(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		defer try.Classify(try.Class{UserFacing: true})
+		panic(`service is under maintenance`)
+	}))
+
+	rew := httptest.NewRecorder()
+	handler.ServeHTTP(rew, httptest.NewRequest(http.MethodGet, `/`, nil))
+	fmt.Println(strings.TrimSpace(rew.Body.String()))
+	// Output:
+	// {"type":"about:blank","title":"Internal Server Error","status":500,"detail":"service is under maintenance"}
+}
+
+func ExampleRecoverer_public() {
+	handler := tryhttp.Recoverer{}.Wrap(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		defer try.DetailPublic(`failed to save the order`)
+		defer try.Detail(`failed to save order 123 to shard 7`)
+		panic(`connection refused`)
+	}))
+
+	rew := httptest.NewRecorder()
+	handler.ServeHTTP(rew, httptest.NewRequest(http.MethodGet, `/`, nil))
+	fmt.Println(strings.TrimSpace(rew.Body.String()))
+	// Output:
+	// {"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed to save the order"}
+}
+
+func ExampleRecoverer_clientDetail() {
+	reg := new(tryhttp.Registry)
+	reg.Func(func(err error) bool { return err.Error() == `invalid page number` }, http.StatusBadRequest)
+
+	handler := tryhttp.Recoverer{Registry: reg, ClientDetail: true}.Wrap(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(errors.New(`invalid page number`))
+	}))
+
+	rew := httptest.NewRecorder()
+	handler.ServeHTTP(rew, httptest.NewRequest(http.MethodGet, `/`, nil))
+	fmt.Println(strings.TrimSpace(rew.Body.String()))
+	// Output:
+	// {"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid page number"}
+}
+
+func ExampleRecoverer_flush() {
+	handler := tryhttp.Recoverer{}.Wrap(http.HandlerFunc(func(rew http.ResponseWriter, _ *http.Request) {
+		_, ok := rew.(http.Hijacker)
+		fmt.Println(`hijacker:`, ok)
+		rew.(http.Flusher).Flush()
+		panic(`failure`)
+	}))
+
+	rew := httptest.NewRecorder()
+	val := func() (val interface{}) {
+		defer func() { val = recover() }()
+		handler.ServeHTTP(rew, httptest.NewRequest(http.MethodGet, `/`, nil))
+		return
+	}()
+	fmt.Println(rew.Flushed, val == http.ErrAbortHandler)
+	// Output:
+	// hijacker: true
+	// true true
+}
+
+func ExampleRecoverer_abort() {
+	handler := tryhttp.Recoverer{}.Wrap(http.HandlerFunc(func(rew http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(rew, `partial`)
+		panic(`failure`)
+	}))
+
+	val := func() (val interface{}) {
+		defer func() { val = recover() }()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, `/`, nil))
+		return
+	}()
+	fmt.Println(val == http.ErrAbortHandler)
+	// Output:
+	// true
+}
+
+func ExampleRegistry_Status() {
+	reg := new(tryhttp.Registry)
+	reg.Is(sql.ErrNoRows, http.StatusNotFound)
+	reg.Func(func(err error) bool { return err.Error() == `forbidden` }, http.StatusForbidden)
+
+	fmt.Println(reg.Status(errors.WithStack(sql.ErrNoRows)))
+	fmt.Println(reg.Status(errors.New(`forbidden`)))
+	fmt.Println(reg.Status(errors.New(`other`)))
+	fmt.Println(reg.Status(try.Retryable(errors.New(`overloaded`), true)))
+	// Output:
+	// 404
+	// 403
+	// 500
+	// 503
+}
+
+func decodeProblem(rew *httptest.ResponseRecorder) (interface{}, error) {
+	var out tryhttp.Problem
+	err := json.Unmarshal(rew.Body.Bytes(), &out)
+	return out, err
+}
+
+type Item struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func ExampleGetJSON() {
+	srv := httptest.NewServer(http.HandlerFunc(func(rew http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case `/item`:
+			fmt.Fprint(rew, `{"id": 1, "name": "one"}`)
+		case `/invalid`:
+			fmt.Fprint(rew, `{"id": "one"}`)
+		default:
+			http.Error(rew, `item not found`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client := tryhttp.Client{}
+	fmt.Printf("%+v\n", tryhttp.GetJSON[Item](client, srv.URL+`/item`))
+
+	err := try.Catch(func() {
+		tryhttp.GetJSON[Item](client, srv.URL+`/missing`)
+	})
+
+	var statusErr *tryhttp.StatusError
+	fmt.Println(errors.As(err, &statusErr))
+	fmt.Println(statusErr.Status, statusErr.Method, strings.TrimSpace(statusErr.Body))
+	fmt.Println(try.HasStack(err))
+
+	err = try.Catch(func() {
+		tryhttp.GetJSON[Item](client, srv.URL+`/invalid`)
+	})
+	fmt.Println(err)
+	// Output:
+	// {ID:1 Name:one}
+	// true
+	// 404 GET item not found
+	// true
+	// failed to decode JSON into tryhttp_test.Item at line 1, column 12, field "id": json: cannot unmarshal string into Go struct field Item.id of type int
+}
+
+func ExamplePostJSON() {
+	srv := httptest.NewServer(http.HandlerFunc(func(rew http.ResponseWriter, req *http.Request) {
+		var item Item
+		try.To(json.NewDecoder(req.Body).Decode(&item))
+		item.ID = 2
+		try.To(json.NewEncoder(rew).Encode(item))
+	}))
+	defer srv.Close()
+
+	client := tryhttp.Client{Header: http.Header{`Authorization`: {`Bearer token`}}}
+	fmt.Printf("%+v\n", tryhttp.PostJSON[Item](client, srv.URL, Item{Name: `two`}))
+	// Output:
+	// {ID:2 Name:two}
+}
+
+func ExampleStatusError() {
+	err := &tryhttp.StatusError{
+		Status: http.StatusBadGateway,
+		Method: http.MethodGet,
+		URL:    `https://example.com/api`,
+		Body:   "upstream\ntimed out",
+	}
+	fmt.Println(err)
+	fmt.Println(try.IsRetryable(err))
+	// Output:
+	// GET https://example.com/api: 502 Bad Gateway: upstream timed out
+	// true
+}
+
+func ExampleClient_Do() {
+	srv := httptest.NewServer(http.HandlerFunc(func(rew http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(rew, req.Header[`Accept`])
+	}))
+	defer srv.Close()
+
+	client := tryhttp.Client{Header: http.Header{`Accept`: {`text/plain`}}}
+
+	// Requests constructed by hand may have no headers at all.
+	req := &http.Request{Method: http.MethodGet, URL: try.Interface(url.Parse(srv.URL)).(*url.URL)}
+	res := client.Do(req)
+	defer res.Body.Close()
+
+	fmt.Println(string(try.ByteSlice(io.ReadAll(res.Body))))
+	fmt.Println(req.Header == nil)
+	// Output:
+	// [text/plain]
+	// true
+}
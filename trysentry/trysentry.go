@@ -0,0 +1,321 @@
+/*
+Forwards errors to Sentry or any service accepting the Sentry "store" event
+format. `Encoder` converts a stack-carrying error into a JSON event, including
+`try.Val`-wrapped panic values and `errors.WithMessage` layers. `Reporter` posts
+such events over HTTP, with retries.
+
+Designed to plug into the "rec" functions:
+
+	defer try.RecWith(reporter.Handle)
+*/
+package trysentry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mitranim/try"
+	"github.com/pkg/errors"
+)
+
+// Event in the Sentry "store" format. Only the fields used by this package are
+// defined.
+type Event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Message     string            `json:"message,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Fingerprint []string          `json:"fingerprint,omitempty"`
+	Exception   *Exceptions       `json:"exception,omitempty"`
+}
+
+// Exception chain of an event, ordered from the root cause to the outermost
+// wrapper, as Sentry expects.
+type Exceptions struct {
+	Values []Exception `json:"values"`
+}
+
+// Single exception in the chain of an event.
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Module     string      `json:"module,omitempty"`
+	Mechanism  *Mechanism  `json:"mechanism,omitempty"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+}
+
+/*
+Describes how an exception was captured. Panic values that aren't errors, which
+`try.Err` wraps into `try.Val`, are reported with the "panic" mechanism type.
+*/
+type Mechanism struct {
+	Type    string `json:"type"`
+	Handled bool   `json:"handled"`
+}
+
+// Stacktrace of an exception. Frames are ordered from the outermost caller to
+// the innermost, as Sentry expects.
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+// Single frame of a stacktrace.
+type Frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+	InApp    bool   `json:"in_app"`
+}
+
+/*
+Converts errors to Sentry events. The zero value is ready to use. Optional
+fields are copied into every event.
+
+`InApp` decides which frames belong to the application. When nil, all frames
+except those of the runtime, the standard library, and the packages of
+"github.com/mitranim/try" are considered in-app; see `try.Frame.IsInternal`.
+
+`Level` is the default level of events, "error" unless specified. Errors
+classified via `try.Classify` use the level matching their severity instead.
+
+`SentryGrouping` omits the fingerprint from events, leaving the grouping to
+Sentry. By default, events are grouped by `try.Fingerprint`.
+
+`Now` is used for event timestamps, and defaults to `time.Now`.
+*/
+type Encoder struct {
+	Level          string
+	Release        string
+	Environment    string
+	ServerName     string
+	Tags           map[string]string
+	InApp          func(try.Frame) bool
+	SentryGrouping bool
+	Now            func() time.Time
+}
+
+/*
+Converts an error to an event. Every significant layer of the error chain, as
+defined by `try.Links`, becomes a separate exception. Panics if the error is
+nil.
+*/
+func (self Encoder) Event(err error) Event {
+	if err == nil {
+		panic(errors.New(`unable to encode nil error`))
+	}
+
+	links := try.Links(err)
+	values := make([]Exception, 0, len(links))
+	for ind := len(links) - 1; ind >= 0; ind-- {
+		values = append(values, self.exception(links[ind]))
+	}
+
+	return Event{
+		EventID:     eventID(),
+		Timestamp:   self.now().UTC().Format(time.RFC3339Nano),
+		Platform:    `go`,
+		Level:       self.level(err),
+		Message:     err.Error(),
+		Release:     self.Release,
+		Environment: self.Environment,
+		ServerName:  self.ServerName,
+		Tags:        self.Tags,
+		Fingerprint: self.fingerprint(err),
+		Exception:   &Exceptions{values},
+	}
+}
+
+// Converts an error to an event, encoded as JSON. Panics if the error is nil.
+func (self Encoder) Encode(err error) []byte {
+	return try.ByteSlice(json.Marshal(self.Event(err)))
+}
+
+func (self Encoder) exception(link try.Link) Exception {
+	out := Exception{Type: link.Type, Value: link.Msg}
+
+	if val, ok := link.Err.(try.Val); ok {
+		out.Value = val.Error()
+		out.Mechanism = &Mechanism{Type: `panic`, Handled: true}
+	}
+
+	if len(link.Stack) > 0 {
+		frames := make([]Frame, 0, len(link.Stack))
+		for ind := len(link.Stack) - 1; ind >= 0; ind-- {
+			frames = append(frames, self.frame(link.Stack[ind]))
+		}
+		out.Module = link.Stack[0].Pkg()
+		out.Stacktrace = &Stacktrace{frames}
+	}
+	return out
+}
+
+func (self Encoder) frame(val try.Frame) Frame {
+	return Frame{
+		Function: val.Name(),
+		Module:   val.Pkg(),
+		Filename: shortPath(val.File),
+		Lineno:   val.Line,
+		InApp:    self.inApp(val),
+	}
+}
+
+func (self Encoder) inApp(val try.Frame) bool {
+	if self.InApp != nil {
+		return self.InApp(val)
+	}
+	return !val.IsInternal()
+}
+
+func (self Encoder) level(err error) string {
+	switch try.ClassOf(err).Severity {
+	case try.SeverityDebug:
+		return `debug`
+	case try.SeverityInfo:
+		return `info`
+	case try.SeverityWarn:
+		return `warning`
+	case try.SeverityError:
+		return `error`
+	case try.SeverityFatal:
+		return `fatal`
+	}
+	if self.Level != `` {
+		return self.Level
+	}
+	return `error`
+}
+
+func (self Encoder) fingerprint(err error) []string {
+	if self.SentryGrouping {
+		return nil
+	}
+	return []string{try.Fingerprint(err)}
+}
+
+func (self Encoder) now() time.Time {
+	if self.Now != nil {
+		return self.Now()
+	}
+	return time.Now()
+}
+
+/*
+Sends errors to a Sentry-compatible "store" endpoint, such as
+"https://sentry.example.com/api/<project>/store/". The zero value is not
+usable; `URL` is required.
+
+When `Key` is set, requests are authenticated via the "X-Sentry-Auth" header.
+`Client` defaults to `http.DefaultClient`.
+
+Failed requests, including responses with status 429 or 5xx, are retried up to
+`Retries` times, sleeping `Backoff` multiplied by the attempt number between
+attempts.
+*/
+type Reporter struct {
+	URL     string
+	Key     string
+	Client  *http.Client
+	Encoder Encoder
+	Retries int
+	Backoff time.Duration
+}
+
+/*
+Encodes and sends the error, retrying on failure. Does nothing if the error is
+nil. Returns the last failure, if all attempts have failed.
+*/
+func (self Reporter) Report(err error) (out error) {
+	defer try.WithMessage(&out, `failed to report error`)
+	defer try.Rec(&out)
+	if err == nil {
+		return
+	}
+
+	body := self.Encoder.Encode(err)
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, out = self.send(body)
+		if out == nil || !retry || attempt >= self.Retries {
+			return
+		}
+		time.Sleep(self.Backoff * time.Duration(attempt+1))
+	}
+}
+
+/*
+Suitable for `try.RecWith` and `try.Fail`. Reports the error, and writes
+reporting failures to stderr, since there's nowhere to return them.
+*/
+func (self Reporter) Handle(err error) {
+	fail := self.Report(err)
+	if fail != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", fail)
+	}
+}
+
+func (self Reporter) send(body []byte) (retry bool, err error) {
+	defer try.Rec(&err)
+	retry = true
+
+	req, err := http.NewRequest(http.MethodPost, self.URL, bytes.NewReader(body))
+	try.To(err)
+	req.Header.Set(`Content-Type`, `application/json`)
+	if self.Key != `` {
+		req.Header.Set(`X-Sentry-Auth`, fmt.Sprintf(
+			`Sentry sentry_version=7, sentry_client=try/1.0, sentry_key=%v`,
+			self.Key,
+		))
+	}
+
+	res, err := self.client().Do(req)
+	try.To(err)
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return false, nil
+	}
+	retry = res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+	return retry, errors.Errorf(`unexpected response status %v`, res.Status)
+}
+
+func (self Reporter) client() *http.Client {
+	if self.Client != nil {
+		return self.Client
+	}
+	return http.DefaultClient
+}
+
+func eventID() string {
+	var buf [16]byte
+	_ = try.Int(rand.Read(buf[:]))
+	return hex.EncodeToString(buf[:])
+}
+
+// Sentry displays "filename" as-is, so the last two path segments are enough
+// to be readable without leaking the build machine's directory layout.
+func shortPath(path string) string {
+	slash := 0
+	for ind := len(path) - 1; ind >= 0; ind-- {
+		if path[ind] == '/' {
+			slash++
+			if slash == 2 {
+				return path[ind+1:]
+			}
+		}
+	}
+	return path
+}
@@ -0,0 +1,108 @@
+package trysentry_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/mitranim/try"
+	"github.com/mitranim/try/trysentry"
+	"github.com/pkg/errors"
+)
+
+func ExampleEncoder_Event() {
+	err := try.Catch(func() {
+		defer try.Detail(`failed to X`)
+		panic(`failure`)
+	})
+
+	event := trysentry.Encoder{}.Event(err)
+	fmt.Println(event.Message)
+
+	for _, val := range event.Exception.Values {
+		fmt.Printf("%v: %v\n", val.Type, val.Value)
+		if val.Mechanism != nil {
+			fmt.Println(`mechanism:`, val.Mechanism.Type)
+		}
+		if val.Stacktrace != nil {
+			frames := val.Stacktrace.Frames
+			last := frames[len(frames)-1]
+			fmt.Println(`innermost:`, last.Function, last.InApp)
+		}
+	}
+	// Output:
+	// failed to X: failure
+	// string: failure
+	// mechanism: panic
+	// innermost: Err false
+	// *errors.withMessage: failed to X
+}
+
+func ExampleEncoder_Event_severity() {
+	err := try.Catch(func() {
+		defer try.Classify(try.Class{Severity: try.SeverityWarn})
+		panic(`disk almost full`)
+	})
+
+	fmt.Println(trysentry.Encoder{}.Event(err).Level)
+	fmt.Println(trysentry.Encoder{Level: `info`}.Event(errors.New(`failure`)).Level)
+	// Output:
+	// warning
+	// info
+}
+
+func ExampleEncoder_Event_sentryGrouping() {
+	err := errors.New(`failure`)
+	fmt.Println(len(trysentry.Encoder{}.Event(err).Fingerprint))
+	fmt.Println(len(trysentry.Encoder{SentryGrouping: true}.Event(err).Fingerprint))
+	// Output:
+	// 1
+	// 0
+}
+
+func ExampleReporter_Report() {
+	var attempts int
+	var event trysentry.Event
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rew http.ResponseWriter, req *http.Request) {
+		attempts++
+		if attempts == 1 {
+			rew.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(req.Body)
+		_ = json.Unmarshal(body, &event)
+		fmt.Println(req.Header.Get(`X-Sentry-Auth`))
+	}))
+	defer srv.Close()
+
+	reporter := trysentry.Reporter{
+		URL:     srv.URL,
+		Key:     `public-key`,
+		Retries: 2,
+		Encoder: trysentry.Encoder{Environment: `test`},
+	}
+
+	fmt.Println(reporter.Report(errors.New(`failure`)))
+	fmt.Println(attempts)
+	fmt.Println(event.Environment, event.Exception.Values[0].Value)
+	// Output:
+	// Sentry sentry_version=7, sentry_client=try/1.0, sentry_key=public-key
+	// <nil>
+	// 2
+	// test failure
+}
+
+func ExampleReporter_Report_failure() {
+	srv := httptest.NewServer(http.HandlerFunc(func(rew http.ResponseWriter, _ *http.Request) {
+		rew.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	reporter := trysentry.Reporter{URL: srv.URL, Retries: 2}
+	fmt.Println(reporter.Report(errors.New(`failure`)))
+	// Output:
+	// failed to report error: unexpected response status 400 Bad Request
+}
@@ -0,0 +1,215 @@
+/*
+Panicking helpers for "database/sql", for the "exceptions" style. `Tx` runs a
+function in a transaction, committing on normal return and rolling back on
+panic:
+
+	trysql.Tx(db, func(tx *sql.Tx) {
+		trysql.Exec(tx, `update accounts set balance = balance - 10 where id = $1`, one)
+		trysql.Exec(tx, `update accounts set balance = balance + 10 where id = $1`, two)
+	})
+
+`QueryRows` and `QueryOne` scan rows into structs or scalars:
+
+	users := trysql.QueryRows[User](db, `select id, name from users`)
+
+All errors carry stacktraces via `try.To`.
+*/
+package trysql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mitranim/try"
+	"github.com/pkg/errors"
+)
+
+// Implemented by `*sql.DB` and `*sql.Conn`. Used by `Tx`.
+type Beginner interface {
+	BeginTx(context.Context, *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Implemented by `*sql.DB`, `*sql.Conn` and `*sql.Tx`. Used by `QueryRows`
+// and `QueryOne`.
+type Querier interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}
+
+// Implemented by `*sql.DB`, `*sql.Conn` and `*sql.Tx`. Used by `Exec`.
+type Execer interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+}
+
+// Shortcut for `TxContext` with `context.Background` and default options.
+func Tx(db Beginner, fun func(*sql.Tx)) {
+	TxContext(context.Background(), db, nil, fun)
+}
+
+/*
+Runs the function in a new transaction. Commits when the function returns
+normally, and panics if the commit fails. Rolls back when the function panics,
+and re-panics with the original error; if the rollback fails too, its error is
+attached to the message, while the original error remains the cause. Also rolls
+back when the function calls `runtime.Goexit`, for example via `t.FailNow` in
+tests, without panicking.
+*/
+func TxContext(ctx context.Context, db Beginner, opt *sql.TxOptions, fun func(*sql.Tx)) {
+	tx, err := db.BeginTx(ctx, opt)
+	try.To(err)
+
+	done := false
+	defer func() {
+		if !done {
+			rollback(tx, recover())
+		}
+	}()
+
+	fun(tx)
+	done = true
+	try.To(tx.Commit())
+}
+
+func rollback(tx *sql.Tx, val interface{}) {
+	fail := tx.Rollback()
+	if val == nil {
+		return
+	}
+
+	err := try.Err(val)
+	if fail != nil && !errors.Is(fail, sql.ErrTxDone) {
+		err = errors.WithMessage(err, `failed to roll back: `+fail.Error())
+	}
+	try.To(err)
+}
+
+// Shortcut for `ExecContext` with `context.Background`.
+func Exec(db Execer, query string, args ...interface{}) sql.Result {
+	return ExecContext(context.Background(), db, query, args...)
+}
+
+// Panicking version of `sql.DB.ExecContext` and its equivalents.
+func ExecContext(ctx context.Context, db Execer, query string, args ...interface{}) sql.Result {
+	out, err := db.ExecContext(ctx, query, args...)
+	try.To(err)
+	return out
+}
+
+// Shortcut for `QueryRowsContext` with `context.Background`.
+func QueryRows[T any](db Querier, query string, args ...interface{}) []T {
+	return QueryRowsContext[T](context.Background(), db, query, args...)
+}
+
+/*
+Runs the query and scans all rows into values of the given type. Structs are
+scanned field by field, see `Scan`. Other types, such as `string`, `time.Time`
+or implementations of `sql.Scanner`, require exactly one column. Panics on
+failure.
+*/
+func QueryRowsContext[T any](ctx context.Context, db Querier, query string, args ...interface{}) []T {
+	rows, err := db.QueryContext(ctx, query, args...)
+	try.To(err)
+	defer rows.Close()
+
+	var out []T
+	for rows.Next() {
+		out = append(out, Scan[T](rows))
+	}
+	try.To(rows.Err())
+	return out
+}
+
+// Shortcut for `QueryOneContext` with `context.Background`.
+func QueryOne[T any](db Querier, query string, args ...interface{}) T {
+	return QueryOneContext[T](context.Background(), db, query, args...)
+}
+
+/*
+Like `QueryRowsContext`, but scans only the first row, ignoring the others.
+Panics with `sql.ErrNoRows` if there are no rows.
+*/
+func QueryOneContext[T any](ctx context.Context, db Querier, query string, args ...interface{}) T {
+	rows, err := db.QueryContext(ctx, query, args...)
+	try.To(err)
+	defer rows.Close()
+
+	if !rows.Next() {
+		try.To(rows.Err())
+		try.To(sql.ErrNoRows)
+	}
+	out := Scan[T](rows)
+	try.To(rows.Close())
+	return out
+}
+
+/*
+Scans the current row into a value of the given type. Must be called after
+`rows.Next`. Panics on failure.
+
+Structs that don't implement `sql.Scanner` are scanned field by field. Each
+column is matched to an exported field whose `db` tag equals the column name,
+or, without the tag, whose name equals the column name case-insensitively,
+ignoring underscores, so that column "user_id" matches field "UserID". Fields
+of embedded structs are included. Fields tagged `db:"-"` are ignored. Columns
+without a matching field cause a panic.
+*/
+func Scan[T any](rows *sql.Rows) (out T) {
+	val := reflect.ValueOf(&out).Elem()
+	if !isStruct(val.Type()) {
+		try.To(rows.Scan(&out))
+		return
+	}
+
+	cols, err := rows.Columns()
+	try.To(err)
+
+	ptrs := make([]interface{}, len(cols))
+	for ind, col := range cols {
+		path := fieldPath(val.Type(), col)
+		if path == nil {
+			try.To(errors.Errorf(`no field in %v for column %q`, val.Type(), col))
+		}
+		ptrs[ind] = val.FieldByIndex(path).Addr().Interface()
+	}
+	try.To(rows.Scan(ptrs...))
+	return
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// Structs implementing `sql.Scanner`, such as `sql.NullString`, are scanned
+// as a whole. `time.Time` is handled by the driver.
+func isStruct(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Struct &&
+		!reflect.PointerTo(typ).Implements(scannerType) &&
+		typ != reflect.TypeOf(time.Time{})
+}
+
+// Index path of the field matching the column, or nil if not found.
+func fieldPath(typ reflect.Type, col string) []int {
+	for ind := 0; ind < typ.NumField(); ind++ {
+		field := typ.Field(ind)
+		tag, tagged := field.Tag.Lookup(`db`)
+		if tag == `-` {
+			continue
+		}
+
+		if field.Anonymous && !tagged && isStruct(field.Type) {
+			path := fieldPath(field.Type, col)
+			if path != nil {
+				return append([]int{ind}, path...)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if (tagged && tag == col) || (!tagged && strings.EqualFold(field.Name, strings.ReplaceAll(col, `_`, ``))) {
+			return []int{ind}
+		}
+	}
+	return nil
+}
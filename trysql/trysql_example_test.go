@@ -0,0 +1,197 @@
+package trysql_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mitranim/try"
+	"github.com/mitranim/try/trysql"
+	"github.com/pkg/errors"
+)
+
+type User struct {
+	ID   int64
+	Name string `db:"user_name"`
+}
+
+func ExampleTx() {
+	db := openDB()
+	defer db.Close()
+
+	trysql.Tx(db, func(tx *sql.Tx) {
+		trysql.Exec(tx, `insert into users`)
+	})
+
+	err := try.Catch(func() {
+		trysql.Tx(db, func(tx *sql.Tx) {
+			trysql.Exec(tx, `insert into users`)
+			trysql.Exec(tx, `fail`)
+		})
+	})
+	fmt.Println(err)
+	fmt.Println(strings.Join(fakeLog, `; `))
+	// Output:
+	// fake failure
+	// begin; exec insert into users; commit; begin; exec insert into users; exec fail; rollback
+}
+
+func ExampleTx_rollbackFailure() {
+	db := openDB()
+	defer db.Close()
+
+	err := try.Catch(func() {
+		trysql.Tx(db, func(tx *sql.Tx) {
+			trysql.Exec(tx, `break rollback`)
+			panic(`failure`)
+		})
+	})
+	fmt.Println(err)
+	fmt.Println(errors.Cause(err))
+	// Output:
+	// failed to roll back: fake rollback failure: failure
+	// failure
+}
+
+func ExampleQueryRows() {
+	db := openDB()
+	defer db.Close()
+
+	for _, val := range trysql.QueryRows[User](db, `select id, user_name from users`) {
+		fmt.Println(val.ID, val.Name)
+	}
+	fmt.Println(trysql.QueryRows[string](db, `select user_name from users`))
+
+	err := try.Catch(func() {
+		trysql.QueryRows[User](db, `select id, email from users`)
+	})
+	fmt.Println(err)
+	// Output:
+	// 1 Alice
+	// 2 Bob
+	// [Alice Bob]
+	// no field in trysql_test.User for column "email"
+}
+
+func ExampleQueryOne() {
+	db := openDB()
+	defer db.Close()
+
+	fmt.Println(trysql.QueryOne[User](db, `select id, user_name from users`).Name)
+
+	err := try.Catch(func() {
+		trysql.QueryOne[User](db, `select id, user_name from nobody`)
+	})
+	fmt.Println(errors.Is(err, sql.ErrNoRows))
+	// Output:
+	// Alice
+	// true
+}
+
+/*
+Minimal fake driver. Recognizes a few hardcoded queries and records the
+statements and transaction events in `fakeLog`.
+*/
+type fakeDriver struct{}
+
+var fakeLog []string
+
+func init() { sql.Register(`trysql_fake`, fakeDriver{}) }
+
+func openDB() *sql.DB {
+	fakeLog = nil
+	return try.Interface(sql.Open(`trysql_fake`, ``)).(*sql.DB)
+}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{}, nil }
+
+type fakeConn struct{ breakRollback bool }
+
+func (self *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{self, query}, nil
+}
+
+func (self *fakeConn) Close() error { return nil }
+
+func (self *fakeConn) Begin() (driver.Tx, error) {
+	fakeLog = append(fakeLog, `begin`)
+	return fakeTx{self}, nil
+}
+
+type fakeTx struct{ conn *fakeConn }
+
+func (self fakeTx) Commit() error {
+	fakeLog = append(fakeLog, `commit`)
+	return nil
+}
+
+func (self fakeTx) Rollback() error {
+	fakeLog = append(fakeLog, `rollback`)
+	if self.conn.breakRollback {
+		self.conn.breakRollback = false
+		return errors.New(`fake rollback failure`)
+	}
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (self fakeStmt) Close() error  { return nil }
+func (self fakeStmt) NumInput() int { return -1 }
+
+func (self fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	fakeLog = append(fakeLog, `exec `+self.query)
+	switch self.query {
+	case `fail`:
+		return nil, errors.New(`fake failure`)
+	case `break rollback`:
+		self.conn.breakRollback = true
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (self fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	head, from, _ := strings.Cut(strings.TrimPrefix(self.query, `select `), ` from `)
+	out := &fakeRows{cols: strings.Split(head, `, `)}
+	if from != `users` {
+		return out, nil
+	}
+
+	for _, user := range []User{{1, `Alice`}, {2, `Bob`}} {
+		var row []driver.Value
+		for _, col := range out.cols {
+			switch col {
+			case `id`:
+				row = append(row, user.ID)
+			case `user_name`:
+				row = append(row, user.Name)
+			default:
+				row = append(row, nil)
+			}
+		}
+		out.rows = append(out.rows, row)
+	}
+	return out, nil
+}
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (self *fakeRows) Columns() []string { return self.cols }
+func (self *fakeRows) Close() error      { return nil }
+
+func (self *fakeRows) Next(out []driver.Value) error {
+	if len(self.rows) == 0 {
+		return io.EOF
+	}
+	copy(out, self.rows[0])
+	self.rows = self.rows[1:]
+	return nil
+}
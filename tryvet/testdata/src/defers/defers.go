@@ -0,0 +1,42 @@
+package defers
+
+import "github.com/mitranim/try"
+
+func deferred() (err error) {
+	defer try.Rec(&err)
+	defer try.Trace()
+	defer try.Detail(`failed`)
+	return
+}
+
+func notDeferred() (err error) {
+	try.Rec(&err) // want `try.Rec must be deferred directly`
+	return
+}
+
+func closure() {
+	defer func() { try.Trace() }() // want `try.Trace must be deferred directly`
+}
+
+func closureWithArgs() {
+	defer func() {
+		try.Detailf(`failed to %v`, `X`) // want `try.Detailf must be deferred directly`
+	}()
+}
+
+func closureWithOtherStatements() {
+	defer func() {
+		println(`cleanup`)
+		try.Ignore() // want `try.Ignore must be deferred directly`
+	}()
+}
+
+func goroutine() {
+	go try.Ignore() // want `try.Ignore must be deferred directly`
+}
+
+func value() {
+	_ = try.Catch(func() {})
+	handler := try.Trace
+	defer handler()
+}
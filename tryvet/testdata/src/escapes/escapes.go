@@ -0,0 +1,88 @@
+package escapes
+
+import (
+	"errors"
+
+	"github.com/mitranim/try"
+)
+
+func Direct() error {
+	try.To(errors.New(`failure`)) // want `Direct returns an error but may panic here`
+	return nil
+}
+
+func Typed() (string, error) {
+	return try.String(``, nil), nil // want `Typed returns an error but may panic here`
+}
+
+func Recovered() (err error) {
+	defer try.Rec(&err)
+	try.To(errors.New(`failure`))
+	return
+}
+
+func Caught() error {
+	return try.Catch(func() {
+		try.To(errors.New(`failure`))
+	})
+}
+
+func ViaHelper() error {
+	helper() // want `ViaHelper returns an error but may panic here`
+	return nil
+}
+
+func ViaSafeHelper() error {
+	_ = safeHelper()
+	return nil
+}
+
+func Immediate() error {
+	func() {
+		try.To(nil) // want `Immediate returns an error but may panic here`
+	}()
+	return nil
+}
+
+func Deferred() error {
+	go try.To(nil)
+	_ = func() { try.To(nil) }
+	return nil
+}
+
+func NoError() {
+	try.To(nil)
+}
+
+func unexported() error {
+	try.To(nil)
+	return nil
+}
+
+type Type struct{}
+
+func (Type) Method() error {
+	try.To(nil) // want `Method returns an error but may panic here`
+	return nil
+}
+
+type private struct{}
+
+func (private) Method() error {
+	try.To(nil)
+	return nil
+}
+
+func helper() { nested() }
+
+func nested() { try.To(nil) }
+
+func safeHelper() (err error) {
+	defer try.Rec(&err)
+	helper()
+	return
+}
+
+func Recursive() error {
+	return Recursive()
+}
@@ -0,0 +1,8 @@
+package exceptions
+
+import "github.com/mitranim/try"
+
+func Direct() error {
+	try.To(nil)
+	return nil
+}
@@ -0,0 +1,19 @@
+// Minimal stub of "github.com/mitranim/try" for analyzer tests.
+package try
+
+func To(error)                                       {}
+func Catch(func()) error                             { return nil }
+func Trace()                                         {}
+func Detail(string)                                  {}
+func Detailf(string, ...interface{})                 {}
+func Ignore()                                        {}
+func Rec(*error)                                     {}
+func RecOnly(*error, func(error) bool)               {}
+func RecWith(func(error))                            {}
+func RecWithMessage(*error, string)                  {}
+func RecWithMessagef(*error, string, ...interface{}) {}
+func WithMessage(*error, string)                     {}
+func WithMessagef(*error, string, ...interface{})    {}
+
+func String(val string, _ error) string { return val }
+func Caught(func()) bool                { return false }
@@ -0,0 +1,65 @@
+package recs
+
+import "github.com/mitranim/try"
+
+var global error
+
+func named() (err error) {
+	defer try.Rec(&err)
+	defer try.RecWithMessage(&err, `failed`)
+	defer try.WithMessagef(&(err), `failed to %v`, `X`)
+	return
+}
+
+func local() error {
+	var err error
+	defer try.Rec(&err) // want `&err passed to try.Rec is not a named result of the enclosing function`
+	return err
+}
+
+func param(err error) {
+	defer try.RecOnly(&err, nil) // want `&err passed to try.RecOnly is not a named result`
+}
+
+func shadowed() (err error) {
+	if err := try.Catch(nil); err != nil {
+		defer try.WithMessage(&err, `failed`) // want `&err passed to try.WithMessage is not a named result`
+	}
+	return
+}
+
+func outer() (err error) {
+	func() {
+		defer try.Rec(&err)
+	}()
+	return
+}
+
+func captured() error {
+	var err error
+	func() {
+		defer try.WithMessage(&err, `failed`)
+		err = try.Catch(nil)
+	}()
+	return err
+}
+
+func capturedParam(err error) error {
+	func() {
+		defer try.Rec(&err)
+	}()
+	return err
+}
+
+func closureLocal() {
+	_ = func() error {
+		var err error
+		defer try.RecWithMessagef(&err, `failed`) // want `&err passed to try.RecWithMessagef is not a named result`
+		return err
+	}
+}
+
+func other(ptr *error) {
+	defer try.Rec(ptr)
+	defer try.Rec(&global)
+}
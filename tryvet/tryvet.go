@@ -0,0 +1,74 @@
+/*
+Static analyzers for code using "github.com/mitranim/try". They catch mistakes
+that compile fine but silently break error handling, such as calling a
+must-be-deferred function without `defer`.
+
+The analyzers are compatible with "golang.org/x/tools/go/analysis". The command
+"github.com/mitranim/try/cmd/tryvet" runs all of them, and can be used with
+`go vet`:
+
+	go install github.com/mitranim/try/cmd/tryvet@latest
+	go vet -vettool=$(which tryvet) ./...
+*/
+package tryvet
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// All analyzers defined by this package.
+var Analyzers = []*analysis.Analyzer{Defer, RecPtr, Escape}
+
+const pkgPath = `github.com/mitranim/try`
+
+/*
+Functions of package "try" that must be deferred directly, because they either
+call `recover()`, which works only when called directly by a deferred function,
+or are meant to modify results after the function returns.
+*/
+var mustDefer = map[string]bool{
+	`Trace`:              true,
+	`Ok`:                 true,
+	`Fail`:               true,
+	`Trans`:              true,
+	`Classify`:           true,
+	`MarkRetryable`:      true,
+	`MarkPermanent`:      true,
+	`MarkRetryableOnly`:  true,
+	`MarkPermanentOnly`:  true,
+	`ClassifyOnly`:       true,
+	`Detail`:             true,
+	`Detailf`:            true,
+	`DetailFunc`:         true,
+	`DetailOnly`:         true,
+	`DetailOnlyf`:        true,
+	`DetailPublic`:       true,
+	`Ignore`:             true,
+	`IgnoreOnly`:         true,
+	`Rec`:                true,
+	`RecOnly`:            true,
+	`RecChan`:            true,
+	`RecWith`:            true,
+	`RecWithMessage`:     true,
+	`RecWithMessagef`:    true,
+	`RecWithMessageFunc`: true,
+	`WithMessage`:        true,
+	`WithMessagef`:       true,
+}
+
+// Returns the package-level function of package "try" called by this
+// expression, if any.
+func tryFunc(info *types.Info, call *ast.CallExpr) *types.Func {
+	fun, _ := typeutil.Callee(info, call).(*types.Func)
+	if fun == nil || fun.Pkg() == nil || fun.Pkg().Path() != pkgPath {
+		return nil
+	}
+	if fun.Type().(*types.Signature).Recv() != nil {
+		return nil
+	}
+	return fun
+}
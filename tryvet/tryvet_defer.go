@@ -0,0 +1,138 @@
+package tryvet
+
+import (
+	"bytes"
+	"go/ast"
+	"go/printer"
+	"go/token"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+/*
+Reports calls to must-be-deferred functions of package "try", such as `Rec`,
+`Trace` or `Detail`, which are not the direct operand of a `defer` statement.
+Such calls compile fine, but silently do nothing, because `recover()` only
+works when called directly by a deferred function.
+
+Suggests fixes for the two common mistakes: wrapping the call in a deferred
+closure, and forgetting `defer` altogether.
+*/
+var Defer = &analysis.Analyzer{
+	Name:     `trydefer`,
+	Doc:      `report must-be-deferred functions of package "try" that are not deferred directly`,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      runDefer,
+}
+
+func runDefer(pass *analysis.Pass) (interface{}, error) {
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	ins.WithStack([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+
+		call := node.(*ast.CallExpr)
+		fun := tryFunc(pass.TypesInfo, call)
+		if fun == nil || !mustDefer[fun.Name()] {
+			return true
+		}
+
+		parent := stack[len(stack)-2]
+		if val, ok := parent.(*ast.DeferStmt); ok && val.Call == call {
+			return true
+		}
+
+		diag := analysis.Diagnostic{
+			Pos:     call.Pos(),
+			End:     call.End(),
+			Message: `try.` + fun.Name() + ` must be deferred directly, as in "defer try.` + fun.Name() + `(...)"; otherwise it has no effect`,
+		}
+
+		if outer := wrappingDefer(stack); outer != nil {
+			diag.SuggestedFixes = []analysis.SuggestedFix{{
+				Message: `Defer try.` + fun.Name() + ` directly`,
+				TextEdits: []analysis.TextEdit{{
+					Pos:     outer.Pos(),
+					End:     outer.End(),
+					NewText: []byte(`defer ` + render(pass.Fset, call)),
+				}},
+			}}
+		} else if _, ok := parent.(*ast.ExprStmt); ok && !inDeferredClosure(stack) {
+			diag.SuggestedFixes = []analysis.SuggestedFix{{
+				Message: `Add defer`,
+				TextEdits: []analysis.TextEdit{{
+					Pos:     call.Pos(),
+					End:     call.Pos(),
+					NewText: []byte(`defer `),
+				}},
+			}}
+		}
+
+		pass.Report(diag)
+		return true
+	})
+	return nil, nil
+}
+
+/*
+Detects the pattern `defer func() { try.X() }()`, where the closure consists of
+the offending call and nothing else. Returns the enclosing defer statement.
+*/
+func wrappingDefer(stack []ast.Node) *ast.DeferStmt {
+	if len(stack) < 6 {
+		return nil
+	}
+	ind := len(stack) - 1
+
+	_, ok := stack[ind-1].(*ast.ExprStmt)
+	if !ok {
+		return nil
+	}
+	block, ok := stack[ind-2].(*ast.BlockStmt)
+	if !ok || len(block.List) != 1 {
+		return nil
+	}
+	lit, ok := stack[ind-3].(*ast.FuncLit)
+	if !ok || lit.Body != block || len(lit.Type.Params.List) > 0 {
+		return nil
+	}
+	call, ok := stack[ind-4].(*ast.CallExpr)
+	if !ok || call.Fun != lit || len(call.Args) > 0 {
+		return nil
+	}
+	outer, ok := stack[ind-5].(*ast.DeferStmt)
+	if !ok || outer.Call != call {
+		return nil
+	}
+	return outer
+}
+
+/*
+A `defer` nested in a deferred closure runs too late to recover, so adding one
+there wouldn't fix anything.
+*/
+func inDeferredClosure(stack []ast.Node) bool {
+	for ind := len(stack) - 1; ind >= 2; ind-- {
+		lit, ok := stack[ind].(*ast.FuncLit)
+		if !ok {
+			continue
+		}
+		call, ok := stack[ind-1].(*ast.CallExpr)
+		if !ok || call.Fun != lit {
+			return false
+		}
+		_, ok = stack[ind-2].(*ast.DeferStmt)
+		return ok
+	}
+	return false
+}
+
+func render(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	_ = printer.Fprint(&buf, fset, node)
+	return buf.String()
+}
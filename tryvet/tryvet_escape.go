@@ -0,0 +1,251 @@
+package tryvet
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+/*
+Reports exported functions and methods that return an `error`, yet may panic
+via `try.To` or the typed "try" functions such as `try.String`, called directly
+or through helpers in the same package, without a recovering deferral such as
+`defer try.Rec(&err)`. Callers of such functions expect errors to be returned,
+not panicked.
+
+Calls inside closures passed to `try.Catch` and similar functions are
+considered handled. Calls inside other closures are ignored, since it's unknown
+when and where they run.
+
+Packages that intentionally use the "exceptions" style can be excluded via the
+"exceptions" flag, which takes a comma-separated list of package paths, where a
+trailing "/..." matches subpackages.
+*/
+var Escape = &analysis.Analyzer{
+	Name: `tryescape`,
+	Doc:  `report exported functions returning errors that may leak panics from package "try"`,
+	Run:  runEscape,
+}
+
+var escapeExceptions string
+
+func init() {
+	Escape.Flags.StringVar(
+		&escapeExceptions,
+		`exceptions`,
+		``,
+		`comma-separated package paths that use the exceptions style; a trailing "/..." matches subpackages`,
+	)
+}
+
+// Functions of package "try" that recover from panics when deferred.
+var recoverFuncs = map[string]bool{
+	`Rec`:                true,
+	`RecOnly`:            true,
+	`RecChan`:            true,
+	`RecWith`:            true,
+	`RecWithMessage`:     true,
+	`RecWithMessagef`:    true,
+	`RecWithMessageFunc`: true,
+	`Ignore`:             true,
+	`IgnoreOnly`:         true,
+}
+
+// Functions of package "try" that recover from panics in the given closure.
+var catchFuncs = map[string]bool{
+	`Catch`:        true,
+	`CatchOnly`:    true,
+	`Caught`:       true,
+	`CaughtOnly`:   true,
+	`Ignoring`:     true,
+	`IgnoringOnly`: true,
+}
+
+func runEscape(pass *analysis.Pass) (interface{}, error) {
+	// Package "try" itself is exempt: it defines the panicking style.
+	if pass.Pkg.Path() == pkgPath || isException(pass.Pkg.Path(), escapeExceptions) {
+		return nil, nil
+	}
+
+	esc := escaper{pass: pass, decls: map[*types.Func]*ast.FuncDecl{}, memo: map[*types.Func]ast.Node{}}
+	var order []*types.Func
+
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			fun, ok := decl.(*ast.FuncDecl)
+			if !ok || fun.Body == nil {
+				continue
+			}
+			obj, _ := pass.TypesInfo.Defs[fun.Name].(*types.Func)
+			if obj != nil {
+				esc.decls[obj] = fun
+				order = append(order, obj)
+			}
+		}
+	}
+
+	for _, obj := range order {
+		decl := esc.decls[obj]
+		if !isExportedAPI(obj) || !returnsError(obj) {
+			continue
+		}
+		site := esc.panics(obj)
+		if site == nil {
+			continue
+		}
+		pass.Reportf(
+			site.Pos(),
+			`%v returns an error but may panic here; add "defer try.Rec(&err)" with a named error result, or exclude the package via -exceptions`,
+			decl.Name.Name,
+		)
+	}
+	return nil, nil
+}
+
+type escaper struct {
+	pass  *analysis.Pass
+	decls map[*types.Func]*ast.FuncDecl
+	memo  map[*types.Func]ast.Node
+}
+
+/*
+Returns the first unrecovered call in the function's body that may panic via
+package "try", or nil. Helpers are checked recursively; recursion is broken by
+treating functions under analysis as non-panicking.
+*/
+func (self *escaper) panics(obj *types.Func) ast.Node {
+	site, ok := self.memo[obj]
+	if ok {
+		return site
+	}
+	self.memo[obj] = nil
+
+	decl := self.decls[obj]
+	if decl == nil || self.recovers(decl.Body) {
+		return nil
+	}
+
+	site = self.find(decl.Body)
+	self.memo[obj] = site
+	return site
+}
+
+// True if the body has a top-level recovering deferral.
+func (self *escaper) recovers(body *ast.BlockStmt) bool {
+	for _, stmt := range body.List {
+		val, ok := stmt.(*ast.DeferStmt)
+		if !ok {
+			continue
+		}
+		fun := tryFunc(self.pass.TypesInfo, val.Call)
+		if fun != nil && recoverFuncs[fun.Name()] {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *escaper) find(body ast.Node) (site ast.Node) {
+	ast.Inspect(body, func(node ast.Node) bool {
+		if site != nil {
+			return false
+		}
+
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+
+		case *ast.GoStmt:
+			return false
+
+		case *ast.CallExpr:
+			// Immediately invoked closures run in place.
+			if lit, ok := node.Fun.(*ast.FuncLit); ok {
+				if !self.recovers(lit.Body) {
+					site = self.find(lit.Body)
+				}
+				return false
+			}
+
+			if fun := tryFunc(self.pass.TypesInfo, node); fun != nil {
+				if catchFuncs[fun.Name()] {
+					return false
+				}
+				if isTryPanicking(fun) {
+					site = node
+					return false
+				}
+				return true
+			}
+
+			fun, _ := typeutil.Callee(self.pass.TypesInfo, node).(*types.Func)
+			if fun != nil && self.decls[fun] != nil && self.panics(fun) != nil {
+				site = node
+				return false
+			}
+		}
+		return true
+	})
+	return
+}
+
+/*
+True for `try.To` and the typed "try" functions, which take a value and an
+error, and return the value.
+*/
+func isTryPanicking(fun *types.Func) bool {
+	if fun.Name() == `To` {
+		return true
+	}
+	sig := fun.Type().(*types.Signature)
+	params := sig.Params()
+	return params.Len() == 2 &&
+		sig.Results().Len() == 1 &&
+		isErrorType(params.At(1).Type()) &&
+		types.Identical(params.At(0).Type(), sig.Results().At(0).Type())
+}
+
+func isExportedAPI(obj *types.Func) bool {
+	if !obj.Exported() {
+		return false
+	}
+	recv := obj.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return true
+	}
+	typ := recv.Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	return ok && named.Obj().Exported()
+}
+
+func returnsError(obj *types.Func) bool {
+	res := obj.Type().(*types.Signature).Results()
+	return res.Len() > 0 && isErrorType(res.At(res.Len()-1).Type())
+}
+
+func isErrorType(typ types.Type) bool {
+	return types.Identical(typ, types.Universe.Lookup(`error`).Type())
+}
+
+func isException(path, list string) bool {
+	for _, pattern := range strings.Split(list, `,`) {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == `` {
+			continue
+		}
+		if pattern == path {
+			return true
+		}
+		prefix := strings.TrimSuffix(pattern, `/...`)
+		if prefix != pattern && (path == prefix || strings.HasPrefix(path, prefix+`/`)) {
+			return true
+		}
+	}
+	return false
+}
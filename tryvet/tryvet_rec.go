@@ -0,0 +1,119 @@
+package tryvet
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+/*
+Reports calls such as `defer try.Rec(&err)` where `err` is a local variable or
+parameter rather than a named result of the enclosing function. Functions such
+as `Rec` write the error to the pointer after the function body has finished,
+so writing to anything other than a result loses the error.
+
+Results of outer functions, and other variables captured by a closure from an
+outer scope, are allowed, because a closure that's called synchronously may
+legitimately write to them.
+*/
+var RecPtr = &analysis.Analyzer{
+	Name:     `tryrec`,
+	Doc:      `report error pointers passed to "rec" functions of package "try" that don't refer to a named result`,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      runRecPtr,
+}
+
+// Functions of package "try" whose first parameter is an error pointer, written
+// after the function body has finished.
+var recPtrFuncs = map[string]bool{
+	`Rec`:                true,
+	`RecOnly`:            true,
+	`RecWithMessage`:     true,
+	`RecWithMessagef`:    true,
+	`RecWithMessageFunc`: true,
+	`WithMessage`:        true,
+	`WithMessagef`:       true,
+}
+
+func runRecPtr(pass *analysis.Pass) (interface{}, error) {
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	ins.WithStack([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+
+		call := node.(*ast.CallExpr)
+		fun := tryFunc(pass.TypesInfo, call)
+		if fun == nil || !recPtrFuncs[fun.Name()] || len(call.Args) == 0 {
+			return true
+		}
+
+		// Only `&name` can be checked. Other pointers may come from anywhere.
+		addr, ok := ast.Unparen(call.Args[0]).(*ast.UnaryExpr)
+		if !ok || addr.Op != token.AND {
+			return true
+		}
+		ident, ok := ast.Unparen(addr.X).(*ast.Ident)
+		if !ok {
+			return true
+		}
+		target, ok := pass.TypesInfo.Uses[ident].(*types.Var)
+		if !ok || target.Parent() == pass.Pkg.Scope() {
+			return true
+		}
+
+		if isResult(pass.TypesInfo, stack, target) {
+			return true
+		}
+
+		pass.Reportf(
+			addr.Pos(),
+			`&%v passed to try.%v is not a named result of the enclosing function; the error will be lost`,
+			ident.Name, fun.Name(),
+		)
+		return true
+	})
+	return nil, nil
+}
+
+/*
+True if the variable is a named result of any function enclosing the node at
+the top of the stack, or is captured by the innermost enclosing closure from an
+outer scope.
+*/
+func isResult(info *types.Info, stack []ast.Node, target *types.Var) bool {
+	innermost := true
+
+	for ind := len(stack) - 1; ind >= 0; ind-- {
+		var typ *ast.FuncType
+		switch val := stack[ind].(type) {
+		case *ast.FuncDecl:
+			typ = val.Type
+		case *ast.FuncLit:
+			typ = val.Type
+			if innermost && (target.Pos() < val.Pos() || target.Pos() >= val.End()) {
+				return true
+			}
+		default:
+			continue
+		}
+		innermost = false
+
+		if typ.Results == nil {
+			continue
+		}
+		for _, field := range typ.Results.List {
+			for _, name := range field.Names {
+				if info.Defs[name] == target {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
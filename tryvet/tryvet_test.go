@@ -0,0 +1,26 @@
+package tryvet_test
+
+import (
+	"testing"
+
+	"github.com/mitranim/try/tryvet"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestDefer(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), tryvet.Defer, `defers`)
+}
+
+func TestRecPtr(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), tryvet.RecPtr, `recs`)
+}
+
+func TestEscape(t *testing.T) {
+	err := tryvet.Escape.Flags.Set(`exceptions`, `exceptions`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tryvet.Escape.Flags.Set(`exceptions`, ``)
+
+	analysistest.Run(t, analysistest.TestData(), tryvet.Escape, `escapes`, `exceptions`)
+}